@@ -0,0 +1,143 @@
+package gomap
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// The binary format:
+//
+//	"GOMP" | version(1 byte) | uvarint len |
+//	len times: uvarint key size | key | uvarint value size | value |
+//	CRC-32(IEEE) of all the bytes before, 4 bytes little-endian
+const (
+	binaryMagic   = "GOMP"
+	binaryVersion = 1
+
+	binaryHeaderSize   = len(binaryMagic) + 1
+	binaryChecksumSize = 4
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*hmap[string, int])(nil)
+	_ encoding.BinaryUnmarshaler = (*hmap[string, int])(nil)
+)
+
+// MarshalBinary - encodes all the elements with the codecs set by WithCodecs.
+//
+//	data, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+func (m *hmap[K, V]) MarshalBinary() ([]byte, error) {
+	data := append([]byte(binaryMagic), binaryVersion)
+	data = binary.AppendUvarint(data, uint64(m.Len()))
+
+	var err error
+	m.Range(func(k K, v V) bool {
+		if data, err = appendEncoded(data, m.keyCodec, k); err != nil {
+			return false
+		}
+		data, err = appendEncoded(data, m.valueCodec, v)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data)), nil
+}
+
+// UnmarshalBinary - replaces the elements of the map with the decoded ones.
+// The map is resized for the stored length first, so loading doesn't grow it.
+// The map isn't changed if the data can't be decoded.
+// Keys decoded as equal ones are reported as an error, the map isn't changed either.
+func (m *hmap[K, V]) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderSize+binaryChecksumSize {
+		return errors.New("gomap: binary data is too short")
+	}
+	if string(data[:len(binaryMagic)]) != binaryMagic {
+		return errors.New("gomap: binary data isn't an encoded map")
+	}
+	if version := data[len(binaryMagic)]; version != binaryVersion {
+		return fmt.Errorf("gomap: unsupported binary version %d", version)
+	}
+
+	payload := data[:len(data)-binaryChecksumSize]
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(data[len(payload):]) {
+		return errors.New("gomap: binary data checksum mismatch")
+	}
+
+	r := payload[binaryHeaderSize:]
+	n, size := binary.Uvarint(r)
+	if size <= 0 {
+		return errors.New("gomap: invalid length of binary data")
+	}
+	r = r[size:]
+	// every element takes at least 2 bytes for the sizes of its key and value
+	if n > uint64(len(r)/2) {
+		return fmt.Errorf("gomap: binary data is too short for %d elements", n)
+	}
+
+	// the elements are put into a new map first,
+	// so the map isn't changed if the data turns out to be invalid
+	loaded := newHmap(int(n), options[K, V]{hasher: m.hasher})
+	for i := uint64(0); i < n; i++ {
+		var err error
+		var k K
+		var v V
+		if k, r, err = decodeNext(r, m.keyCodec); err != nil {
+			return fmt.Errorf("gomap: key %d: %w", i, err)
+		}
+		if v, r, err = decodeNext(r, m.valueCodec); err != nil {
+			return fmt.Errorf("gomap: value %d: %w", i, err)
+		}
+		loaded.Put(k, v)
+	}
+	if len(r) != 0 {
+		return fmt.Errorf("gomap: %d bytes left after %d elements", len(r), n)
+	}
+	if loaded.len != int(n) {
+		return fmt.Errorf("gomap: %d duplicate keys in binary data", int(n)-loaded.len)
+	}
+
+	m.replace(loaded)
+	return nil
+}
+
+// replace - replaces the elements of the map with the elements of <src>, the buckets of <src> are taken
+func (m *hmap[K, V]) replace(src *hmap[K, V]) {
+	src.finishGrowth()
+
+	m.startWriting()
+
+	m.clear()
+	m.B, m.hintB = src.B, src.hintB
+	m.buckets = src.buckets
+	m.len, m.noverflow = src.len, src.noverflow
+
+	m.finishWriting()
+}
+
+// appendEncoded appends the size of the encoded <v> and the encoded bytes
+func appendEncoded[T any](data []byte, codec Codec[T], v T) ([]byte, error) {
+	encoded, err := codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(encoded)))
+	return append(data, encoded...), nil
+}
+
+// decodeNext decodes a size prefixed element, returns the rest of the data
+func decodeNext[T any](data []byte, codec Codec[T]) (T, []byte, error) {
+	var v T
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return v, nil, errors.New("truncated data")
+	}
+
+	v, err := codec.Decode(data[n : n+int(size)])
+	return v, data[n+int(size):], err
+}
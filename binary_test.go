@@ -0,0 +1,204 @@
+package gomap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	t.Run("round trip with gob", func(t *testing.T) {
+		m := New[string, []int](10).(*hmap[string, []int])
+		for i := 0; i < 100; i++ {
+			m.Put(fmt.Sprintf("key%d", i), []int{i, i * 2})
+		}
+
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := New[string, []int](0).(*hmap[string, []int])
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		isEqual(t, loaded.Len(), 100)
+		for i := 0; i < 100; i++ {
+			isEqual(t, loaded.Get(fmt.Sprintf("key%d", i)), []int{i, i * 2})
+		}
+	})
+
+	t.Run("round trip with codecs", func(t *testing.T) {
+		opts := []Option[string, int64]{WithCodecs[string, int64](StringCodec{}, BinaryCodec[int64]{})}
+		m := NewWithOptions(0, opts...).(*hmap[string, int64])
+		m.Put("a", 1)
+		m.Put("", -1)
+
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// header(5) + len(1) + "a" with 1(11) + "" with -1(10) + checksum(4)
+		isEqual(t, len(data), 31)
+
+		loaded := NewWithOptions(0, opts...).(*hmap[string, int64])
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		isEqual(t, loaded.Get("a"), int64(1))
+		isEqual(t, loaded.Get(""), int64(-1))
+	})
+
+	t.Run("presized", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 1000; i++ {
+			m.Put(i, i)
+		}
+		data, _ := m.MarshalBinary()
+
+		// the loaded map doesn't grow and doesn't keep the old elements
+		loaded := New[int, int](0).(*hmap[int, int])
+		loaded.Put(-1, -1)
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		isEqual(t, loaded.B, fitB(1000))
+		isEqual(t, loaded.isGrowing(), false)
+		isEqual(t, loaded.Len(), 1000)
+		_, ok := loaded.Get2(-1)
+		isEqual(t, ok, false)
+		mustValidate(t, loaded)
+	})
+
+	t.Run("during growth", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 50; i++ {
+			m.Put(i, i)
+		}
+		m.ForceGrow(false)
+		m.EvacuateStep(1)
+		data, _ := m.MarshalBinary()
+
+		loaded := New[int, int](0).(*hmap[int, int])
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			isEqual(t, loaded.Get(i), i)
+		}
+	})
+
+	t.Run("codec error", func(t *testing.T) {
+		failing := errors.New("failing codec")
+		m := NewWithOptions(0, WithCodecs[int, int](failingCodec{failing}, BinaryCodec[int]{})).(*hmap[int, int])
+		m.Put(1, 1)
+
+		if _, err := m.MarshalBinary(); !errors.Is(err, failing) {
+			t.Fatalf("got %v, want %v", err, failing)
+		}
+	})
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	opts := []Option[string, string]{WithCodecs[string, string](StringCodec{}, StringCodec{})}
+	m := NewWithOptions(0, opts...).(*hmap[string, string])
+	m.Put("key", "value")
+	valid, _ := m.MarshalBinary()
+
+	// withChecksum replaces the checksum, so the data gets to the decoding
+	withChecksum := func(data []byte) []byte {
+		payload := data[:len(data)-binaryChecksumSize]
+		return binary.LittleEndian.AppendUint32(payload, crc32.ChecksumIEEE(payload))
+	}
+	modified := func(f func(data []byte) []byte) []byte {
+		return f(append([]byte{}, valid...))
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "too short"},
+		{"magic", modified(func(d []byte) []byte { d[0] = 'X'; return d }), "isn't an encoded map"},
+		{"version", modified(func(d []byte) []byte { d[4] = 2; return d }), "unsupported binary version 2"},
+		{"checksum", modified(func(d []byte) []byte { d[len(d)-1]++; return d }), "checksum mismatch"},
+		{"corrupted", modified(func(d []byte) []byte { d[8]++; return d }), "checksum mismatch"},
+		{"huge length", modified(func(d []byte) []byte { d[5] = 100; return withChecksum(d) }), "too short for 100 elements"},
+		{"truncated", modified(func(d []byte) []byte { return withChecksum(d[:len(d)-6]) }), "value 0: truncated data"},
+		// the old checksum is left after the elements
+		{"trailing bytes", modified(func(d []byte) []byte { return withChecksum(append(d, 0, 0, 0, 0)) }), "4 bytes left after 1 elements"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			loaded := NewWithOptions(0, opts...).(*hmap[string, string])
+			loaded.Put("old", "old")
+
+			err := loaded.UnmarshalBinary(tc.data)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got %v, want an error with %q", err, tc.err)
+			}
+			// the map isn't changed
+			isEqual(t, loaded.Len(), 1)
+			isEqual(t, loaded.Get("old"), "old")
+		})
+	}
+
+	t.Run("duplicate keys", func(t *testing.T) {
+		// both keys are encoded as "k"
+		m := NewWithOptions(0, WithCodecs[string, string](prefixCodec{}, StringCodec{})).(*hmap[string, string])
+		m.Put("k1", "1")
+		m.Put("k2", "2")
+		data, _ := m.MarshalBinary()
+
+		loaded := NewWithOptions(0, opts...).(*hmap[string, string])
+		loaded.Put("old", "old")
+		err := loaded.UnmarshalBinary(data)
+		if err == nil || !strings.Contains(err.Error(), "1 duplicate keys") {
+			t.Fatalf("got %v, want a duplicate keys error", err)
+		}
+		// the map isn't changed
+		isEqual(t, loaded.Len(), 1)
+		isEqual(t, loaded.Get("old"), "old")
+		mustValidate(t, loaded)
+	})
+}
+
+func TestCodecs(t *testing.T) {
+	type point struct{ X, Y int32 }
+
+	data, err := BinaryCodec[point]{}.Encode(point{1, -2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isEqual(t, len(data), 8)
+	p, err := BinaryCodec[point]{}.Decode(data)
+	isEqual(t, err, nil)
+	isEqual(t, p, point{1, -2})
+
+	if _, err := (BinaryCodec[point]{}).Decode(append(data, 0)); err == nil {
+		t.Fatal("expected an error for an extra byte")
+	}
+	if _, err := (BinaryCodec[string]{}).Encode("not fixed size"); err == nil {
+		t.Fatal("expected an error for a string")
+	}
+
+	data, err = GobCodec[map[string]int]{}.Encode(map[string]int{"a": 1})
+	isEqual(t, err, nil)
+	decoded, err := GobCodec[map[string]int]{}.Decode(data)
+	isEqual(t, err, nil)
+	isEqual(t, decoded, map[string]int{"a": 1})
+}
+
+type failingCodec struct{ err error }
+
+func (c failingCodec) Encode(int) ([]byte, error) { return nil, c.err }
+func (c failingCodec) Decode([]byte) (int, error) { return 0, c.err }
+
+// prefixCodec keeps only the first byte of a string
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(v string) ([]byte, error)    { return []byte(v[:1]), nil }
+func (prefixCodec) Decode(data []byte) (string, error) { return string(data), nil }
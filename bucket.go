@@ -1,10 +1,5 @@
 package gomap
 
-import (
-	"fmt"
-	"strings"
-)
-
 const (
 	bucketSize = 8
 
@@ -54,22 +49,56 @@ bucketLoop:
 // Put - adds value to the bucket.
 // if the value for a given key already exists, it'll be replaced
 // if there is no place in this bucket for a new value, new overflow bucket will be created
-func (b *bucket[K, V]) Put(key K, topHash uint8, value V) (isAdded bool) {
+// and isOverflowed will be true
+func (b *bucket[K, V]) Put(key K, topHash uint8, value V) (isAdded, isOverflowed bool) {
+	bkt, i, found := b.find(key, topHash)
+	if found {
+		bkt.values[i] = value
+		return false, false
+	}
+
+	// if we didn't find a place to put
+	if i == bucketSize {
+		bkt.overflow = &bucket[K, V]{}
+		bkt = bkt.overflow
+		i = 0
+		isOverflowed = true
+	}
+
+	bkt.putAt(key, topHash, value, uint(i))
+
+	return true, isOverflowed
+}
+
+// testHookFind - called by find, tests count passes over bucket chains with it
+var testHookFind func()
+
+// find - looks for the cell with the given key in a single pass over the bucket chain.
+// if the key exists, returns its bucket and index and found == true.
+// otherwise returns the first empty cell where the key can be put,
+// or the last bucket of the chain and i == bucketSize if there are no empty cells.
+func (b *bucket[K, V]) find(key K, topHash uint8) (bkt *bucket[K, V], i int, found bool) {
+	if testHookFind != nil {
+		testHookFind()
+	}
+
 	var insertIdx int
 	var insertBkt *bucket[K, V]
 
-	bkt := b
-	for bkt != nil {
+	for bkt = b; ; bkt = bkt.overflow {
 		for i := range bkt.tophash {
 			// comparing topHash bits, not keys
 			// because we can store there flags describing cell state such as cell is empty, cell is evacuating etc.
 			// also it's faster than comparing keys
 			top := bkt.tophash[i]
 			if top != topHash {
+				// there are no filled cells further, so the key doesn't exist.
+				// use the first empty cell we've met
 				if top == emptyRest {
-					insertBkt = bkt
-					insertIdx = i
-					break
+					if insertBkt == nil {
+						return bkt, i, false
+					}
+					return insertBkt, insertIdx, false
 				}
 
 				if insertBkt == nil && isCellEmpty(top) {
@@ -84,29 +113,18 @@ func (b *bucket[K, V]) Put(key K, topHash uint8, value V) (isAdded bool) {
 				continue
 			}
 
-			bkt.values[i] = value
-			return false
+			return bkt, i, true
 		}
 
 		if bkt.overflow == nil {
-			// if we didn't find a place to put
-			if insertBkt == nil {
-				bkt.overflow = &bucket[K, V]{}
-				insertBkt = bkt.overflow
-				break
-			} else { // break if we found a place for the value
-				break
-			}
+			break
 		}
-
-		bkt = bkt.overflow
 	}
 
-	insertBkt.keys[insertIdx] = key
-	insertBkt.values[insertIdx] = value
-	insertBkt.tophash[insertIdx] = topHash
-
-	return true
+	if insertBkt == nil {
+		return bkt, bucketSize, false
+	}
+	return insertBkt, insertIdx, false
 }
 
 func (b *bucket[K, V]) putAt(key K, topHash uint8, value V, idx uint) {
@@ -115,8 +133,9 @@ func (b *bucket[K, V]) putAt(key K, topHash uint8, value V, idx uint) {
 	b.values[idx] = value
 }
 
-// Delete - deletes an element with the given key
-func (b *bucket[K, V]) Delete(key K, topHash uint8) (deleted bool) {
+// Delete - deletes an element with the given key and returns its value.
+// If an element doesn't exist for the given key returns zero value for <V> and false.
+func (b *bucket[K, V]) Delete(key K, topHash uint8) (value V, deleted bool) {
 	bkt := b
 	for bkt != nil {
 		for i := range bkt.tophash {
@@ -124,20 +143,66 @@ func (b *bucket[K, V]) Delete(key K, topHash uint8) (deleted bool) {
 			if top != topHash {
 				// if there are no filled cells we return
 				if top == emptyRest {
-					return false
+					return value, false
 				}
 				continue
 			}
 
 			if bkt.keys[i] == key {
-				bkt.tophash[i] = emptyCell
-				return true
+				value = bkt.values[i]
+				b.deleteAt(bkt, i)
+				return value, true
 			}
 		}
 		bkt = bkt.overflow
 	}
 
-	return false
+	return value, false
+}
+
+// deleteAt - deletes the element at the cell <i> of <bkt>.
+// the method must be called on the first bucket of the chain.
+func (b *bucket[K, V]) deleteAt(bkt *bucket[K, V], i int) {
+	// zero the key and the value, so the GC doesn't keep objects they point to
+	bkt.keys[i] = *new(K)
+	bkt.values[i] = *new(V)
+	bkt.tophash[i] = emptyCell
+	b.markEmptyRest(bkt, i)
+}
+
+// markEmptyRest - if the bucket chain ends with a bunch of emptyCell states
+// after deleting the cell <i> in <bkt>, changes those to emptyRest states.
+// so Get and Delete can stop scanning earlier.
+// the method must be called on the first bucket of the chain.
+func (b *bucket[K, V]) markEmptyRest(bkt *bucket[K, V], i int) {
+	if i == bucketSize-1 {
+		if bkt.overflow != nil && bkt.overflow.tophash[0] != emptyRest {
+			return
+		}
+	} else if bkt.tophash[i+1] != emptyRest {
+		return
+	}
+
+	for {
+		bkt.tophash[i] = emptyRest
+		if i == 0 {
+			if bkt == b {
+				return // beginning of the initial bucket, we're done
+			}
+			// find the previous bucket, continue at its last cell
+			prev := b
+			for ; prev.overflow != bkt; prev = prev.overflow {
+			}
+			bkt = prev
+			i = bucketSize - 1
+		} else {
+			i--
+		}
+
+		if bkt.tophash[i] != emptyCell {
+			return
+		}
+	}
 }
 
 func isCellEmpty(val uint8) bool {
@@ -148,13 +213,3 @@ func (b bucket[K, V]) isEvacuated() bool {
 	h := b.tophash[0]
 	return h > emptyCell && h < minTopHash
 }
-
-func (b bucket[K, V]) debug() string {
-	str := strings.Builder{}
-	str.WriteString("bucket[")
-	for i := range b.keys {
-		str.WriteString(fmt.Sprintf("%v:%v ", b.keys[i], b.values[i]))
-	}
-
-	return str.String()[:str.Len()-1] + "]"
-}
@@ -0,0 +1,44 @@
+package gomap
+
+import "sync/atomic"
+
+// guardWriting is set in the guard state while a goroutine is writing to the map.
+// the rest of the bits count readers.
+const guardWriting = 1 << 30
+
+// accessGuard - detects concurrent misuse of a map in the checked mode.
+// unlike the hashWriting flag it's changed with atomic operations,
+// so two overlapping accesses always see each other.
+type accessGuard struct {
+	state atomic.Int32
+}
+
+func (g *accessGuard) startWriting() {
+	if !g.state.CompareAndSwap(0, guardWriting) {
+		if g.state.Load()&guardWriting != 0 {
+			panic("concurrent map writes")
+		}
+		panic("concurrent map read and map write")
+	}
+}
+
+func (g *accessGuard) finishWriting() {
+	// fails if a reader has come while we were writing
+	if !g.state.CompareAndSwap(guardWriting, 0) {
+		g.state.Add(-guardWriting)
+		panic("concurrent map read and map write")
+	}
+}
+
+// startReading - registers a reader. panics with msg if a goroutine is writing to the map.
+// readers don't conflict with each other.
+func (g *accessGuard) startReading(msg string) {
+	if g.state.Add(1)&guardWriting != 0 {
+		g.state.Add(-1)
+		panic(msg)
+	}
+}
+
+func (g *accessGuard) finishReading() {
+	g.state.Add(-1)
+}
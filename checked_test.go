@@ -0,0 +1,240 @@
+package gomap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// blockingHasher blocks the first hashing of the key -1 after arm until the release channel is closed.
+// it allows to stop a goroutine in the middle of a map operation.
+type blockingHasher struct {
+	armed   atomic.Bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHasher() *blockingHasher {
+	return &blockingHasher{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (h *blockingHasher) Hash(key int) uint64 {
+	if key == -1 && h.armed.CompareAndSwap(true, false) {
+		h.block()
+	}
+	return uint64(key)
+}
+
+func (h *blockingHasher) arm() {
+	h.armed.Store(true)
+}
+
+// block - signals that a goroutine is in the middle of an operation and waits for the release
+func (h *blockingHasher) block() {
+	close(h.entered)
+	<-h.release
+}
+
+func mustPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		got := recover()
+		if got == nil {
+			t.Fatalf("expected panic %q", want)
+		}
+		isEqual(t, got, want)
+	}()
+	f()
+}
+
+func TestAccessChecks(t *testing.T) {
+	// blocked starts the given operation in another goroutine,
+	// the operation blocks in the middle, meanwhile f is called
+	blocked := func(t *testing.T, op func(m Hashmap[int, int], h *blockingHasher), f func(m Hashmap[int, int])) {
+		hasher := newBlockingHasher()
+		m := NewWithOptions(8, WithHasher[int, int](hasher), WithAccessChecks[int, int]())
+		for i := -1; i < 8; i++ {
+			m.Put(i, i)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			op(m, hasher)
+		}()
+
+		<-hasher.entered
+		f(m)
+		close(hasher.release)
+		<-done
+
+		// the map is usable after the operations finished
+		m.Put(100, 100)
+		isEqual(t, m.Get(100), 100)
+	}
+
+	// keys are hashed before the map is accessed, so a write is blocked in the callback of Update
+	put := func(m Hashmap[int, int], h *blockingHasher) {
+		m.(Upserter[int, int]).Update(-1, func(old int, ok bool) (int, bool) {
+			h.block()
+			return old, true
+		})
+	}
+	// and a read is blocked in an iteration, which hashes the keys of old buckets during growth
+	iterate := func(m Hashmap[int, int], h *blockingHasher) {
+		m.(GrowthController).ForceGrow(false)
+		h.arm()
+		m.Range(func(k, v int) bool { return true })
+	}
+
+	t.Run("write and write", func(t *testing.T) {
+		blocked(t, put, func(m Hashmap[int, int]) {
+			mustPanic(t, "concurrent map writes", func() { m.Put(1, 1) })
+			mustPanic(t, "concurrent map writes", func() { m.Delete(1) })
+			mustPanic(t, "concurrent map writes", func() { m.(Clearer).Clear() })
+		})
+	})
+
+	t.Run("write and read", func(t *testing.T) {
+		blocked(t, put, func(m Hashmap[int, int]) {
+			mustPanic(t, "concurrent map read and map write", func() { m.Get(1) })
+		})
+	})
+
+	t.Run("read and write", func(t *testing.T) {
+		blocked(t, iterate, func(m Hashmap[int, int]) {
+			mustPanic(t, "concurrent map read and map write", func() { m.Put(1, 1) })
+		})
+	})
+
+	t.Run("write and iteration", func(t *testing.T) {
+		blocked(t, put, func(m Hashmap[int, int]) {
+			mustPanic(t, "concurrent map iteration and map write", func() {
+				m.Range(func(k, v int) bool { return true })
+			})
+		})
+	})
+
+	t.Run("concurrent reads", func(t *testing.T) {
+		blocked(t, iterate, func(m Hashmap[int, int]) {
+			isEqual(t, m.Get(1), 1)
+			isEqual(t, m.Len(), 9)
+			m.Range(func(k, v int) bool { return true })
+		})
+	})
+
+	t.Run("writes during iteration", func(t *testing.T) {
+		m := NewWithOptions(8, WithAccessChecks[int, int]())
+		for i := 0; i < 8; i++ {
+			m.Put(i, i)
+		}
+
+		// the same goroutine may change the map between iteration steps
+		m.Range(func(k, v int) bool {
+			m.Delete(k)
+			m.Put(k+100, v)
+			return true
+		})
+	})
+}
+
+// iterations are reads, so any number of them may run at the same time. run it with -race
+func TestConcurrentIterations(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option[int, int]
+	}{
+		{"unchecked", nil},
+		{"checked", []Option[int, int]{WithAccessChecks[int, int]()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := 100
+			// every round starts with a new map, the first iterations of which set the iterator flags
+			for round := 0; round < 20; round++ {
+				m := NewWithOptions(0, tc.opts...)
+				for i := 0; i < n; i++ {
+					m.Put(i, i)
+				}
+				// iterations check the old buckets during growth
+				m.(GrowthController).ForceGrow(false)
+				m.(GrowthController).EvacuateStep(2)
+
+				wg := sync.WaitGroup{}
+				for g := 0; g < 8; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						got := 0
+						m.Range(func(k, v int) bool {
+							got++
+							return true
+						})
+						if got != n {
+							t.Errorf("got %d elements, want %d", got, n)
+						}
+						m.Get(g)
+					}()
+				}
+				wg.Wait()
+			}
+		})
+	}
+}
+
+// TestRangeAccessChecks - iterations of the open addressing maps take the read guard for every element
+func TestRangeAccessChecks(t *testing.T) {
+	for _, impl := range []struct {
+		name   string
+		newMap func() (Hashmap[int, int], *accessGuard)
+	}{
+		{"swiss", func() (Hashmap[int, int], *accessGuard) {
+			m := NewSwiss(0, WithAccessChecks[int, int]())
+			return m, m.(*swissMap[int, int]).guard
+		}},
+		{"robin hood", func() (Hashmap[int, int], *accessGuard) {
+			m := NewRobinHood(0, WithAccessChecks[int, int]())
+			return m, m.(*robinHoodMap[int, int]).guard
+		}},
+	} {
+		t.Run(impl.name, func(t *testing.T) {
+			m, guard := impl.newMap()
+			for i := 0; i < 20; i++ {
+				m.Put(i, i)
+			}
+
+			// a writer in another goroutine holds the guard, the hashWriting flag isn't seen yet
+			guard.startWriting()
+			mustPanic(t, "concurrent map iteration and map write", func() {
+				m.Range(func(k, v int) bool { return true })
+			})
+			guard.finishWriting()
+
+			// a write is blocked in the callback of Update while the map is iterated
+			entered, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(done)
+				m.(Upserter[int, int]).Update(1, func(old int, ok bool) (int, bool) {
+					close(entered)
+					<-release
+					return old, true
+				})
+			}()
+
+			<-entered
+			mustPanic(t, "concurrent map iteration and map write", func() {
+				m.Range(func(k, v int) bool { return true })
+			})
+			close(release)
+			<-done
+
+			// the same goroutine may change the map between iteration steps
+			m.Range(func(k, v int) bool {
+				m.Delete(k)
+				m.Put(k+100, v)
+				return true
+			})
+			isEqual(t, m.Len(), 20)
+		})
+	}
+}
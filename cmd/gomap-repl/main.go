@@ -0,0 +1,245 @@
+// gomap-repl is an interactive shell over a map with string keys and values.
+// It prints the bucket and the tophash every key gets, and lets you drive
+// the incremental growth by hand.
+//
+// Commands:
+//
+//	put <key> <value>    # puts the key
+//	get <key>            # prints the value
+//	del <key>            # deletes the key
+//	range                # prints all the elements in the iteration order
+//	stats                # prints the shape of the map
+//	dump [json] [all]    # prints the buckets, all - with empty cells
+//	grow [same]          # starts growth to the double or the same size
+//	step-evacuate [n]    # evacuates the next n old buckets, 1 by default
+//	seed [n]             # rebuilds the map with a fixed hash seed, a random one without n
+//	help
+//	quit
+//
+// Usage:
+//
+//	gomap-repl -size 20 -seed 1
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	gomap "github.com/w1kend/go-map"
+)
+
+const help = `commands:
+  put <key> <value>    puts the key
+  get <key>            prints the value
+  del <key>            deletes the key
+  range                prints all the elements in the iteration order
+  stats                prints the shape of the map
+  dump [json] [all]    prints the buckets, all - with empty cells
+  grow [same]          starts growth to the double or the same size
+  step-evacuate [n]    evacuates the next n old buckets, 1 by default
+  seed [n]             rebuilds the map with a fixed hash seed, a random one without n
+  help
+  quit
+`
+
+var errQuit = errors.New("quit")
+
+func main() {
+	size := flag.Int("size", 8, "the initial size of the map")
+	seed := flag.Int64("seed", -1, "a fixed hash seed, random if negative")
+	flag.Parse()
+
+	r := newREPL(*size, os.Stdout)
+	if *seed >= 0 {
+		r.reseed(uint64(*seed), true)
+	}
+	if err := r.run(os.Stdin, true); err != nil {
+		fmt.Fprintln(os.Stderr, "gomap-repl:", err)
+		os.Exit(1)
+	}
+}
+
+type repl struct {
+	m    gomap.Hashmap[string, string]
+	size int
+	out  io.Writer
+}
+
+func newREPL(size int, out io.Writer) *repl {
+	return &repl{m: gomap.New[string, string](size), size: size, out: out}
+}
+
+// run executes commands line by line until the input ends or quit
+func (r *repl) run(in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(r.out, "> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+
+		err := r.exec(strings.Fields(scanner.Text()))
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+		}
+	}
+}
+
+func (r *repl) exec(fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	cmd, args := fields[0], fields[1:]
+	switch {
+	case cmd == "put" && len(args) == 2:
+		r.m.Put(args[0], args[1])
+		r.printLocation(args[0])
+	case cmd == "get" && len(args) == 1:
+		if v, ok := r.m.Get2(args[0]); ok {
+			fmt.Fprintf(r.out, "%q\n", v)
+		} else {
+			fmt.Fprintln(r.out, "not found")
+		}
+		r.printLocation(args[0])
+	case cmd == "del" && len(args) == 1:
+		if _, ok := r.m.(gomap.Popper[string, string]).Pop(args[0]); !ok {
+			fmt.Fprintln(r.out, "not found")
+		}
+	case cmd == "range" && len(args) == 0:
+		r.m.Range(func(k, v string) bool {
+			_, tophash, bucket := r.m.(gomap.Locator[string]).Locate(k)
+			fmt.Fprintf(r.out, "%q: %q\tbucket=%d tophash=%d\n", k, v, bucket, tophash)
+			return true
+		})
+		fmt.Fprintf(r.out, "len=%d\n", r.m.Len())
+	case cmd == "stats" && len(args) == 0:
+		r.printStats()
+	case cmd == "dump" && len(args) <= 2:
+		opts := gomap.DumpOptions{}
+		for _, arg := range args {
+			switch arg {
+			case "json":
+				opts.Format = gomap.DumpJSON
+			case "all":
+				opts.ShowEmpty = true
+			default:
+				return fmt.Errorf("unknown dump option %q", arg)
+			}
+		}
+		return r.m.(gomap.Dumper).Dump(r.out, opts)
+	case cmd == "grow" && (len(args) == 0 || len(args) == 1 && args[0] == "same"):
+		r.m.(gomap.GrowthController).ForceGrow(len(args) == 1)
+		r.printGrowth()
+	case cmd == "step-evacuate" && len(args) <= 1:
+		n := 1
+		if len(args) == 1 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil {
+				return err
+			}
+		}
+		r.m.(gomap.GrowthController).EvacuateStep(n)
+		r.printGrowth()
+	case cmd == "seed" && len(args) <= 1:
+		if len(args) == 0 {
+			r.reseed(0, false)
+			fmt.Fprintf(r.out, "rehashed %d elements\n", r.m.Len())
+			break
+		}
+		seed, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		r.reseed(seed, true)
+		fmt.Fprintf(r.out, "rehashed %d elements\n", r.m.Len())
+	case cmd == "help":
+		fmt.Fprint(r.out, help)
+	case cmd == "quit" || cmd == "exit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %q, try help", strings.Join(fields, " "))
+	}
+
+	return nil
+}
+
+// printLocation prints where the key is placed in the main array
+// and in the old one during growth
+func (r *repl) printLocation(key string) {
+	hash, tophash, bucket := r.m.(gomap.Locator[string]).Locate(key)
+	fmt.Fprintf(r.out, "hash=%#016x tophash=%d bucket=%d", hash, tophash, bucket)
+
+	stats := r.m.(gomap.StatsReporter).Stats()
+	if stats.Growing {
+		fmt.Fprintf(r.out, " old bucket=%d", hash&(stats.OldBuckets-1))
+	}
+	fmt.Fprintln(r.out)
+}
+
+func (r *repl) printStats() {
+	s := r.m.(gomap.StatsReporter).Stats()
+	fmt.Fprintf(r.out, "len=%d B=%d buckets=%d overflow=%d load=%.2f\n", s.Len, s.B, s.Buckets, s.OverflowBuckets, s.LoadFactor)
+	fmt.Fprintf(r.out, "chains: max=%d mean=%.2f tophash collisions=%d\n", s.MaxChain, s.MeanChain, s.TophashCollisions)
+	fmt.Fprintf(r.out, "memory=%dB\n", s.MemoryBytes)
+	r.printGrowth()
+}
+
+func (r *repl) printGrowth() {
+	s := r.m.(gomap.StatsReporter).Stats()
+	switch {
+	case !s.Growing:
+		fmt.Fprintf(r.out, "not growing, B=%d\n", s.B)
+	case s.SameSizeGrow:
+		fmt.Fprintf(r.out, "same size growth: %d of %d old buckets evacuated\n", s.Evacuated, s.OldBuckets)
+	case s.Shrinking:
+		fmt.Fprintf(r.out, "shrinking: %d of %d old buckets evacuated\n", s.Evacuated, s.OldBuckets)
+	default:
+		fmt.Fprintf(r.out, "growth: %d of %d old buckets evacuated\n", s.Evacuated, s.OldBuckets)
+	}
+}
+
+// reseed rebuilds the map with a new hasher and puts all the elements into it
+func (r *repl) reseed(seed uint64, fixed bool) {
+	var opts []gomap.Option[string, string]
+	if fixed {
+		opts = append(opts, gomap.WithHasher[string, string](seededHasher(seed)))
+	}
+
+	m := gomap.NewWithOptions(max(r.size, r.m.Len()), opts...)
+	r.m.Range(func(k, v string) bool {
+		m.Put(k, v)
+		return true
+	})
+	r.m = m
+}
+
+// seededHasher - FNV-1a mixed with the seed and finalized like splitmix64,
+// so tophashes and buckets are reproducible between runs
+func seededHasher(seed uint64) gomap.HasherFunc[string] {
+	return func(key string) uint64 {
+		h := uint64(14695981039346656037) ^ seed
+		for i := 0; i < len(key); i++ {
+			h ^= uint64(key[i])
+			h *= 1099511628211
+		}
+
+		h ^= h >> 30
+		h *= 0xbf58476d1ce4e5b9
+		h ^= h >> 27
+		h *= 0x94d049bb133111eb
+		h ^= h >> 31
+		return h
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gomap "github.com/w1kend/go-map"
+)
+
+func TestREPL(t *testing.T) {
+	out := bytes.Buffer{}
+	r := newREPL(8, &out)
+	r.reseed(1, true)
+
+	script := `
+put a 1
+put b 2
+get a
+get missing
+del b
+del b
+range
+grow
+step-evacuate 10
+stats
+dump json
+bogus
+quit
+put c 3
+`
+	if err := r.run(strings.NewReader(script), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the seed makes hashes reproducible
+	hash, _, _ := r.m.(gomap.Locator[string]).Locate("a")
+	if hash != seededHasher(1)("a") {
+		t.Fatalf("hash of a is %#x, want %#x", hash, seededHasher(1)("a"))
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		`"1"`,
+		"not found",
+		`"a": "1"`,
+		"growth: 0 of 1 old buckets evacuated",
+		"not growing, B=1",
+		`"numEvacuated"`,
+		`unknown command "bogus"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output doesn't contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"b": "2"`) {
+		t.Fatalf("deleted key b is in range:\n%s", got)
+	}
+	if r.m.Len() != 1 {
+		t.Fatalf("len is %d, want 1: commands after quit shouldn't run", r.m.Len())
+	}
+}
+
+func TestREPLReseed(t *testing.T) {
+	r := newREPL(8, &bytes.Buffer{})
+	for _, k := range []string{"a", "b", "c"} {
+		r.m.Put(k, k)
+	}
+
+	r.reseed(42, true)
+	if r.m.Len() != 3 {
+		t.Fatalf("len is %d after reseed, want 3", r.m.Len())
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if v := r.m.Get(k); v != k {
+			t.Fatalf("got %q for %q after reseed", v, k)
+		}
+	}
+}
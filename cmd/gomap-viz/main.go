@@ -0,0 +1,168 @@
+// gomap-viz runs a script of Put/Delete operations on a map with string keys
+// and renders the bucket layout after every step in the Graphviz DOT language.
+//
+// A script is a list of operations, one per line:
+//
+//	put <key> [value]   # puts the key, the value is the step number by default
+//	del <key>           # deletes the key
+//	fill <prefix> <n>   # puts n keys <prefix>0..<prefix>n-1
+//
+// Usage:
+//
+//	gomap-viz > steps.dot                      # all the steps to stdout
+//	gomap-viz -script ops.txt -out ./steps     # a file per step
+//	gomap-viz -out ./steps -format svg         # requires dot from Graphviz
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gomap "github.com/w1kend/go-map"
+)
+
+// the default script grows a map of the default size from 8 buckets to 16.
+// every write after that evacuates up to 2 old buckets
+const defaultScript = `
+fill key 52
+put key52   # starts the growth
+put key53
+del key3
+put key54
+del key5
+put key55
+put key56
+`
+
+type op struct {
+	name  string // put or del
+	key   string
+	value string
+}
+
+func main() {
+	scriptPath := flag.String("script", "", "a file with the operations, the default script is used if empty")
+	size := flag.Int("size", 40, "the initial size of the map")
+	out := flag.String("out", "", "a directory for a file per step, stdout if empty")
+	format := flag.String("format", "dot", "output format: dot or svg")
+	flag.Parse()
+
+	if err := run(*scriptPath, *size, *out, *format); err != nil {
+		fmt.Fprintln(os.Stderr, "gomap-viz:", err)
+		os.Exit(1)
+	}
+}
+
+func run(scriptPath string, size int, out, format string) error {
+	if format != "dot" && format != "svg" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if format == "svg" && out == "" {
+		return errors.New("svg requires -out")
+	}
+
+	script := io.Reader(strings.NewReader(defaultScript))
+	if scriptPath != "" {
+		f, err := os.Open(scriptPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		script = f
+	}
+	ops, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+
+	if out != "" {
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return err
+		}
+	}
+
+	m := gomap.New[string, string](size)
+	for step, op := range ops {
+		switch op.name {
+		case "put":
+			m.Put(op.key, op.value)
+		case "del":
+			m.Delete(op.key)
+		}
+
+		dot := bytes.Buffer{}
+		fmt.Fprintf(&dot, "// step %d: %s %s\n", step, op.name, op.key)
+		if err := m.(gomap.DOTWriter).WriteDOT(&dot); err != nil {
+			return err
+		}
+
+		if out == "" {
+			if _, err := os.Stdout.Write(dot.Bytes()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeStep(out, step, format, dot.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseScript reads operations, fill is expanded into puts
+func parseScript(r io.Reader) ([]op, error) {
+	var ops []op
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "put" && (len(fields) == 2 || len(fields) == 3):
+			value := strconv.Itoa(len(ops))
+			if len(fields) == 3 {
+				value = fields[2]
+			}
+			ops = append(ops, op{name: "put", key: fields[1], value: value})
+		case fields[0] == "del" && len(fields) == 2:
+			ops = append(ops, op{name: "del", key: fields[1]})
+		case fields[0] == "fill" && len(fields) == 3:
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			for i := 0; i < n; i++ {
+				ops = append(ops, op{name: "put", key: fields[1] + strconv.Itoa(i), value: strconv.Itoa(len(ops))})
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown operation %q", line, scanner.Text())
+		}
+	}
+
+	return ops, scanner.Err()
+}
+
+func writeStep(dir string, step int, format string, dot []byte) error {
+	path := filepath.Join(dir, fmt.Sprintf("step-%03d.%s", step, format))
+	if format == "dot" {
+		return os.WriteFile(path, dot, 0o644)
+	}
+
+	cmd := exec.Command("dot", "-Tsvg", "-o", path)
+	cmd.Stdin = bytes.NewReader(dot)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
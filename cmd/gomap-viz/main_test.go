@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	ops, err := parseScript(strings.NewReader(`
+# comment
+fill k 2
+put a 10 # inline comment
+del k0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []op{
+		{name: "put", key: "k0", value: "0"},
+		{name: "put", key: "k1", value: "1"},
+		{name: "put", key: "a", value: "10"},
+		{name: "del", key: "k0"},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("got %+v, want %+v", ops, want)
+	}
+
+	for _, script := range []string{"get a", "put", "del a b", "fill k x"} {
+		if _, err := parseScript(strings.NewReader(script)); err == nil {
+			t.Fatalf("expected an error for %q", script)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := run("", 40, dir, "dot"); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, _ := parseScript(strings.NewReader(defaultScript))
+	files, _ := filepath.Glob(filepath.Join(dir, "*.dot"))
+	if len(files) != len(ops) {
+		t.Fatalf("got %d files, want %d", len(files), len(ops))
+	}
+
+	// the growth is in progress after the first put over the load factor
+	step, err := os.ReadFile(filepath.Join(dir, "step-052.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(step), "numEvacuated=") {
+		t.Fatal("the evacuation cursor isn't rendered during growth")
+	}
+}
@@ -0,0 +1,62 @@
+package gomap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+)
+
+// Codec - encodes keys or values for MarshalBinary and decodes them for UnmarshalBinary.
+// Decode gets exactly the bytes returned by Encode.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// GobCodec - encodes every element with encoding/gob.
+// It works for most types, but repeats the type information for every element.
+// It's the default codec for keys and values.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// BinaryCodec - encodes fixed-size values(numbers, bools, arrays and structs of them)
+// with encoding/binary in little-endian byte order.
+type BinaryCodec[T any] struct{}
+
+func (BinaryCodec[T]) Encode(v T) ([]byte, error) {
+	return binary.Append(nil, binary.LittleEndian, v)
+}
+
+func (BinaryCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	n, err := binary.Decode(data, binary.LittleEndian, &v)
+	if err == nil && n != len(data) {
+		err = fmt.Errorf("%d bytes left after decoding %T", len(data)-n, v)
+	}
+	return v, err
+}
+
+// StringCodec - stores strings as is.
+type StringCodec struct{}
+
+func (StringCodec) Encode(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (StringCodec) Decode(data []byte) (string, error) {
+	return string(data), nil
+}
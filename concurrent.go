@@ -0,0 +1,282 @@
+package gomap
+
+import (
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// ConcurrentMap - a map which is safe for concurrent use by multiple goroutines.
+// Keys are spread over shards by their hash. Each shard is an ordinary map
+// guarded by its own RWMutex, so operations on different shards don't block each other.
+type ConcurrentMap[K comparable, V any] struct {
+	hasher     Hasher[K]
+	shards     []shard[K, V]
+	mask       uint64            // # of shards - 1
+	stringLess func(a, b K) bool // String sorts keys by it, nil for the iteration order
+}
+
+type shard[K comparable, V any] struct {
+	sync.RWMutex
+	m *hmap[K, V]
+}
+
+// NewConcurrent - creates a new concurrent map for <size> elements split into <shards> shards.
+// The number of shards is rounded up to a power of two. If shards <= 0, it is chosen by GOMAXPROCS.
+func NewConcurrent[K comparable, V any](size int, shards int, opts ...Option[K, V]) *ConcurrentMap[K, V] {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0) * 4
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	o := newOptions(opts)
+	c := &ConcurrentMap[K, V]{
+		hasher:     o.hasher,
+		shards:     make([]shard[K, V], n),
+		mask:       uint64(n - 1),
+		stringLess: o.stringLess,
+	}
+	for i := range c.shards {
+		c.shards[i].m = newHmap(size/n, o)
+	}
+
+	return c
+}
+
+// shardFor returns the shard for the given key and the key's hash.
+// the shard is chosen by the hash bits which aren't used by shards for a bucket index or a tophash.
+// shards use the same hasher, so the hash is passed on and the key is hashed once per operation
+func (c *ConcurrentMap[K, V]) shardFor(key K) (*shard[K, V], uint64) {
+	hash := c.hasher.Hash(key)
+	return &c.shards[(hash>>32)&c.mask], hash
+}
+
+func (c *ConcurrentMap[K, V]) Get(key K) V {
+	v, _ := c.Get2(key)
+	return v
+}
+
+func (c *ConcurrentMap[K, V]) Get2(key K) (V, bool) {
+	s, hash := c.shardFor(key)
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.m.get2(key, hash)
+}
+
+func (c *ConcurrentMap[K, V]) Put(key K, value V) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	s.m.put(key, hash, value)
+}
+
+func (c *ConcurrentMap[K, V]) Delete(key K) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	s.m.pop(key, hash)
+}
+
+// LoadOrStore - returns the existing value for the key if present.
+// Otherwise, it stores and returns the given value.
+// The loaded result is true if the value was loaded, false if stored.
+func (c *ConcurrentMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return c.GetOrPut(key, value)
+}
+
+func (c *ConcurrentMap[K, V]) Update(key K, f func(old V, ok bool) (V, bool)) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	s.m.update(key, hash, f)
+}
+
+func (c *ConcurrentMap[K, V]) GetOrPut(key K, value V) (actual V, loaded bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.getOrPut(key, hash, value)
+}
+
+func (c *ConcurrentMap[K, V]) Compute(key K, f func(old V, ok bool) (V, bool)) (actual V, ok bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.compute(key, hash, f)
+}
+
+func (c *ConcurrentMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.swap(key, hash, value)
+}
+
+// LoadAndDelete - deletes the value for a key, returning the previous value if any.
+// The loaded result reports whether the key was present.
+func (c *ConcurrentMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return c.Pop(key)
+}
+
+func (c *ConcurrentMap[K, V]) Pop(key K) (V, bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.pop(key, hash)
+}
+
+// CompareAndSwap - swaps the old and new values for key
+// if the value stored in the map is equal to old.
+// It panics if V is not comparable, the same as sync.Map does.
+func (c *ConcurrentMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	s.m.update(key, hash, func(v V, ok bool) (V, bool) {
+		swapped = ok && any(v) == any(old)
+		return new, swapped
+	})
+	return swapped
+}
+
+// CompareAndDelete - deletes the entry for key if its value is equal to old.
+// It panics if V is not comparable, the same as sync.Map does.
+func (c *ConcurrentMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	s, hash := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	s.m.compute(key, hash, func(v V, ok bool) (V, bool) {
+		deleted = ok && any(v) == any(old)
+		return v, ok && !deleted
+	})
+	return deleted
+}
+
+// Range - calls f for each key and value in the map.
+// Range doesn't correspond to any consistent snapshot of the map:
+// each shard is copied under its read lock and f is called without any lock held,
+// so f may modify the map.
+func (c *ConcurrentMap[K, V]) Range(f func(k K, v V) bool) {
+	var keys []K
+	var values []V
+	for i := range c.shards {
+		s := &c.shards[i]
+
+		keys, values = keys[:0], values[:0]
+		s.RLock()
+		s.m.Range(func(k K, v V) bool {
+			keys = append(keys, k)
+			values = append(values, v)
+			return true
+		})
+		s.RUnlock()
+
+		for j := range keys {
+			k, v := keys[j], values[j]
+			// the shard isn't locked, it could be changed since the copy.
+			// skip deleted keys and return current values. NaNs can't be looked up
+			if k == k {
+				var ok bool
+				if v, ok = c.Get2(k); !ok {
+					continue
+				}
+			}
+			if !f(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (c *ConcurrentMap[K, V]) RangeSorted(less func(a, b K) bool, f func(k K, v V) bool) {
+	rangeSorted(c.Range, less, f)
+}
+
+func (c *ConcurrentMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Range(yield)
+	}
+}
+
+func (c *ConcurrentMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+func (c *ConcurrentMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// Len - returns the number of elements.
+// Shards are counted one by one, so the result may be stale under concurrent writes.
+func (c *ConcurrentMap[K, V]) Len() int {
+	n := 0
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.RLock()
+		n += s.m.Len()
+		s.RUnlock()
+	}
+	return n
+}
+
+func (c *ConcurrentMap[K, V]) Shrink() {
+	c.eachShard(func(m *hmap[K, V]) { m.Shrink() })
+}
+
+func (c *ConcurrentMap[K, V]) Compact() {
+	c.eachShard(func(m *hmap[K, V]) { m.Compact() })
+}
+
+func (c *ConcurrentMap[K, V]) Clear() {
+	c.eachShard(func(m *hmap[K, V]) { m.Clear() })
+}
+
+func (c *ConcurrentMap[K, V]) Reset(size int) {
+	n := len(c.shards)
+	c.eachShard(func(m *hmap[K, V]) { m.Reset(size / n) })
+}
+
+// eachShard calls f for each shard under its write lock
+func (c *ConcurrentMap[K, V]) eachShard(f func(m *hmap[K, V])) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.Lock()
+		f(s.m)
+		s.Unlock()
+	}
+}
+
+func (c *ConcurrentMap[K, V]) String() string {
+	return formatMap(c.Range, c.stringLess)
+}
+
+var (
+	_ Hashmap[string, int]      = (*ConcurrentMap[string, int])(nil)
+	_ Sequencer[string, int]    = (*ConcurrentMap[string, int])(nil)
+	_ Popper[string, int]       = (*ConcurrentMap[string, int])(nil)
+	_ Upserter[string, int]     = (*ConcurrentMap[string, int])(nil)
+	_ SortedRanger[string, int] = (*ConcurrentMap[string, int])(nil)
+	_ Shrinker                  = (*ConcurrentMap[string, int])(nil)
+	_ Clearer                   = (*ConcurrentMap[string, int])(nil)
+)
@@ -0,0 +1,187 @@
+package gomap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrentMap(t *testing.T) {
+	m := NewConcurrent[string, int](0, 4)
+	isEqual(t, len(m.shards), 4)
+
+	m.Put("a", 1)
+	m.Put("b", 2)
+	isEqual(t, m.Get("a"), 1)
+	isEqual(t, m.Len(), 2)
+
+	v, loaded := m.LoadOrStore("a", 10)
+	isEqual(t, v, 1)
+	isEqual(t, loaded, true)
+	v, loaded = m.LoadOrStore("c", 3)
+	isEqual(t, v, 3)
+	isEqual(t, loaded, false)
+
+	isEqual(t, m.CompareAndSwap("a", 2, 20), false)
+	isEqual(t, m.CompareAndSwap("a", 1, 20), true)
+	isEqual(t, m.Get("a"), 20)
+	isEqual(t, m.CompareAndSwap("x", 0, 1), false)
+
+	isEqual(t, m.CompareAndDelete("b", 3), false)
+	isEqual(t, m.CompareAndDelete("b", 2), true)
+	_, ok := m.Get2("b")
+	isEqual(t, ok, false)
+
+	v, loaded = m.LoadAndDelete("c")
+	isEqual(t, v, 3)
+	isEqual(t, loaded, true)
+	_, loaded = m.LoadAndDelete("c")
+	isEqual(t, loaded, false)
+
+	isEqual(t, m.String(), "go-map[a:20]")
+
+	// Range allows modifications of the map
+	m.Range(func(k string, v int) bool {
+		m.Put(k+k, v)
+		return true
+	})
+	isEqual(t, m.Get("aa"), 20)
+
+	m.Clear()
+	isEqual(t, m.Len(), 0)
+}
+
+func TestConcurrentMapHashOnce(t *testing.T) {
+	if validateWrites {
+		t.Skip("the invariants are checked after every write, it hashes all the keys")
+	}
+	hashes := 0
+	hasher := HasherFunc[int](func(k int) uint64 {
+		hashes++
+		return uint64(k)
+	})
+	m := NewConcurrent(100, 4, WithHasher[int, int](hasher))
+
+	m.Put(1, 1)
+	m.Get2(1)
+	m.Update(1, func(old int, ok bool) (int, bool) { return 2, true })
+	m.Compute(2, func(old int, ok bool) (int, bool) { return 2, true })
+	m.GetOrPut(3, 3)
+	m.Swap(3, 4)
+	m.Delete(3)
+	isEqual(t, hashes, 7)
+
+	// copying a shard doesn't hash, every key is looked up again once
+	hashes = 0
+	m.Range(func(k, v int) bool { return true })
+	isEqual(t, hashes, 2)
+}
+
+func TestConcurrentMapRace(t *testing.T) {
+	const (
+		goroutines = 8
+		n          = 1000
+	)
+	m := NewConcurrent[int, int](0, 0)
+
+	t.Run("put get delete", func(t *testing.T) {
+		wg := sync.WaitGroup{}
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					k := g*n + i
+					m.Put(k, k)
+					isEqual(t, m.Get(k), k)
+					if i%2 == 0 {
+						m.Delete(k)
+					}
+				}
+				m.Range(func(k, v int) bool { return true })
+			}(g)
+		}
+		wg.Wait()
+
+		isEqual(t, m.Len(), goroutines*n/2)
+		m.Clear()
+	})
+
+	t.Run("concurrent ranges", func(t *testing.T) {
+		for i := 0; i < n; i++ {
+			m.Put(i, i)
+		}
+
+		// iterations of the same shard run together under its read lock
+		wg := sync.WaitGroup{}
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got := 0
+				m.Range(func(k, v int) bool {
+					got++
+					isEqual(t, m.Get(k), v)
+					return true
+				})
+				isEqual(t, got, n)
+			}()
+		}
+		wg.Wait()
+		m.Clear()
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		wg := sync.WaitGroup{}
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					for {
+						v, _ := m.LoadOrStore(i%10, 0)
+						if m.CompareAndSwap(i%10, v, v+1) {
+							break
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		for k := 0; k < 10; k++ {
+			isEqual(t, m.Get(k), goroutines*n/10)
+		}
+	})
+
+	t.Run("load or store and load and delete", func(t *testing.T) {
+		stored, deleted := atomic.Int64{}, atomic.Int64{}
+		parallel := func(f func(key int)) {
+			wg := sync.WaitGroup{}
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < n; i++ {
+						f(-i - 1)
+					}
+				}()
+			}
+			wg.Wait()
+		}
+
+		parallel(func(key int) {
+			if _, loaded := m.LoadOrStore(key, key); !loaded {
+				stored.Add(1)
+			}
+		})
+		parallel(func(key int) {
+			if _, loaded := m.LoadAndDelete(key); loaded {
+				deleted.Add(1)
+			}
+		})
+
+		isEqual(t, stored.Load(), int64(n))
+		isEqual(t, deleted.Load(), int64(n))
+	})
+}
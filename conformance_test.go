@@ -0,0 +1,107 @@
+package gomap_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	gomap "github.com/w1kend/go-map"
+	"github.com/w1kend/go-map/gomaptest"
+)
+
+func TestConformance(t *testing.T) {
+	impls := []struct {
+		name    string
+		factory gomaptest.Factory
+	}{
+		{
+			name: "hmap",
+			factory: gomaptest.Factory{
+				Strings: gomap.New[string, int],
+				Floats:  gomap.New[float64, int],
+				Ints:    gomap.New[int, int],
+			},
+		},
+		{
+			name: "checked hmap",
+			factory: gomaptest.Factory{
+				Strings: func(size int) gomap.Hashmap[string, int] {
+					return gomap.NewWithOptions(size, gomap.WithAccessChecks[string, int]())
+				},
+				Floats: func(size int) gomap.Hashmap[float64, int] {
+					return gomap.NewWithOptions(size, gomap.WithAccessChecks[float64, int]())
+				},
+				Ints: func(size int) gomap.Hashmap[int, int] {
+					return gomap.NewWithOptions(size, gomap.WithAccessChecks[int, int]())
+				},
+			},
+		},
+		{
+			name: "swiss",
+			factory: gomaptest.Factory{
+				Strings: func(size int) gomap.Hashmap[string, int] { return gomap.NewSwiss[string, int](size) },
+				Floats:  func(size int) gomap.Hashmap[float64, int] { return gomap.NewSwiss[float64, int](size) },
+				Ints:    func(size int) gomap.Hashmap[int, int] { return gomap.NewSwiss[int, int](size) },
+			},
+		},
+		{
+			name: "robin hood",
+			factory: gomaptest.Factory{
+				Strings: func(size int) gomap.Hashmap[string, int] { return gomap.NewRobinHood[string, int](size) },
+				Floats:  func(size int) gomap.Hashmap[float64, int] { return gomap.NewRobinHood[float64, int](size) },
+				Ints:    func(size int) gomap.Hashmap[int, int] { return gomap.NewRobinHood[int, int](size) },
+			},
+		},
+		{
+			name: "concurrent",
+			factory: gomaptest.Factory{
+				Strings: func(size int) gomap.Hashmap[string, int] { return gomap.NewConcurrent[string, int](size, 4) },
+				Floats:  func(size int) gomap.Hashmap[float64, int] { return gomap.NewConcurrent[float64, int](size, 4) },
+				Ints:    func(size int) gomap.Hashmap[int, int] { return gomap.NewConcurrent[int, int](size, 4) },
+			},
+		},
+		{
+			name: "only Hashmap",
+			factory: gomaptest.Factory{
+				Strings: func(size int) gomap.Hashmap[string, int] { return make(stdMap[string, int], size) },
+				Floats:  func(size int) gomap.Hashmap[float64, int] { return make(stdMap[float64, int], size) },
+				Ints:    func(size int) gomap.Hashmap[int, int] { return make(stdMap[int, int], size) },
+			},
+		},
+	}
+
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			gomaptest.Run(t, impl.factory)
+		})
+	}
+}
+
+// stdMap - a minimal implementation without the optional interfaces,
+// their tests are skipped by the suite
+type stdMap[K comparable, V any] map[K]V
+
+func (m stdMap[K, V]) Get(key K) V          { return m[key] }
+func (m stdMap[K, V]) Get2(key K) (V, bool) { v, ok := m[key]; return v, ok }
+func (m stdMap[K, V]) Put(key K, value V)   { m[key] = value }
+func (m stdMap[K, V]) Delete(key K)         { delete(m, key) }
+func (m stdMap[K, V]) Len() int             { return len(m) }
+
+func (m stdMap[K, V]) Range(f func(k K, v V) bool) {
+	for k, v := range m {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+func (m stdMap[K, V]) String() string {
+	buf := strings.Builder{}
+	buf.WriteString("go-map[")
+	m.Range(func(k K, v V) bool {
+		buf.WriteString(fmt.Sprintf("%v:%v ", k, v))
+		return true
+	})
+
+	return strings.TrimRight(buf.String(), " ") + "]"
+}
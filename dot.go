@@ -0,0 +1,86 @@
+package gomap
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// DOTWriter - implemented by maps which can render their layout in the Graphviz DOT language.
+//
+//	gomap.New[string, int](10).(gomap.DOTWriter).WriteDOT(os.Stdout)
+type DOTWriter interface {
+	WriteDOT(w io.Writer) error
+}
+
+var _ DOTWriter = (*hmap[string, int])(nil)
+
+// cell colors by tophash state
+var dotColors = map[string]string{
+	"filled":          "white",
+	"emptyCell":       "gray90",
+	"emptyRest":       "gray75",
+	"evacuatedFirst":  "lightblue",
+	"evacuatedSecond": "lightskyblue",
+	"evacuatedEmpty":  "lightcyan",
+}
+
+// WriteDOT - renders the main buckets, overflow chains, old buckets
+// and the evacuation cursor as a Graphviz graph.
+func (m *hmap[K, V]) WriteDOT(w io.Writer) error {
+	buf := strings.Builder{}
+	buf.WriteString("digraph gomap {\n")
+	buf.WriteString("\trankdir=LR;\n")
+	buf.WriteString("\tnode [shape=plaintext fontname=monospace fontsize=10];\n")
+	fmt.Fprintf(&buf, "\tlabel=%q;\n", fmt.Sprintf("len=%d B=%d flags=[%s]", m.len, m.B, strings.Join(flagNames(m.allFlags()), " ")))
+
+	writeDOTCluster(&buf, "buckets", "buckets", dumpBuckets(m.buckets, true))
+	if m.isGrowing() {
+		writeDOTCluster(&buf, "old", "old buckets", dumpBuckets(*m.oldbuckets, true))
+
+		// the cursor points to the next old bucket to evacuate
+		fmt.Fprintf(&buf, "\tcursor [shape=cds style=filled fillcolor=gold label=%q];\n",
+			fmt.Sprintf("numEvacuated=%d/%d", m.numEvacuated, m.numOldBuckets()))
+		fmt.Fprintf(&buf, "\tcursor -> old_%d_0;\n", m.numEvacuated)
+	}
+
+	buf.WriteString("}\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// writeDOTCluster writes a node per bucket of the chain, the chain is linked with overflow edges
+func writeDOTCluster(buf *strings.Builder, prefix, label string, buckets []dumpBucket) {
+	fmt.Fprintf(buf, "\tsubgraph cluster_%s {\n", prefix)
+	fmt.Fprintf(buf, "\t\tlabel=%q;\n", label)
+
+	for _, b := range buckets {
+		for i, cells := range b.Chain {
+			title := fmt.Sprintf("%d", b.Index)
+			if i > 0 {
+				title = fmt.Sprintf("%d overflow %d", b.Index, i)
+			}
+			if b.Evacuated {
+				title += " (evacuated)"
+			}
+
+			fmt.Fprintf(buf, "\t\t%s_%d_%d [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">", prefix, b.Index, i)
+			fmt.Fprintf(buf, "<tr><td colspan=\"%d\"><b>%s</b></td></tr><tr>", bucketSize, title)
+			for _, c := range cells {
+				text := c.State
+				if c.Key != nil {
+					text = fmt.Sprintf("%s<br/>top=%d", html.EscapeString(*c.Key), c.Tophash)
+				}
+				fmt.Fprintf(buf, "<td bgcolor=%q>%s</td>", dotColors[c.State], text)
+			}
+			buf.WriteString("</tr></table>>];\n")
+
+			if i > 0 {
+				fmt.Fprintf(buf, "\t\t%s_%d_%d -> %s_%d_%d [label=\"overflow\"];\n", prefix, b.Index, i-1, prefix, b.Index, i)
+			}
+		}
+	}
+
+	buf.WriteString("\t}\n")
+}
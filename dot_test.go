@@ -0,0 +1,45 @@
+package gomap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	// all the keys are in the bucket 0
+	zero := HasherFunc[string](func(string) uint64 { return 0 })
+	m := NewWithOptions(10, WithHasher[string, int](zero)).(*hmap[string, int])
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "<i>"} {
+		m.Put(k, 1)
+	}
+
+	dot := func() string {
+		t.Helper()
+		buf := strings.Builder{}
+		err := m.WriteDOT(&buf)
+		isEqual(t, err, nil)
+		return buf.String()
+	}
+
+	got := dot()
+	isEqual(t, strings.HasPrefix(got, "digraph gomap {\n"), true)
+	isEqual(t, strings.HasSuffix(got, "}\n"), true)
+	isEqual(t, strings.Count(got, "{"), strings.Count(got, "}"))
+	isEqual(t, strings.Contains(got, `label="len=9 B=1 flags=[]";`), true)
+	isEqual(t, strings.Contains(got, "subgraph cluster_buckets {"), true)
+	isEqual(t, strings.Contains(got, "buckets_0_0 -> buckets_0_1 [label=\"overflow\"];"), true)
+	// keys are escaped in HTML labels
+	isEqual(t, strings.Contains(got, "&lt;i&gt;<br/>top=5"), true)
+	isEqual(t, strings.Contains(got, "cluster_old"), false)
+
+	t.Run("growth", func(t *testing.T) {
+		m.moveToNewBuckets(m.B+1, 0)
+		m.evacuate(0)
+		got := dot()
+
+		isEqual(t, strings.Contains(got, "subgraph cluster_old {"), true)
+		isEqual(t, strings.Contains(got, "0 (evacuated)"), true)
+		isEqual(t, strings.Contains(got, `label="numEvacuated=1/2"`), true)
+		isEqual(t, strings.Contains(got, "cursor -> old_1_0;"), true)
+	})
+}
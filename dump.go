@@ -0,0 +1,212 @@
+package gomap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DumpFormat - output format of Dump
+type DumpFormat int
+
+const (
+	DumpText DumpFormat = iota
+	DumpJSON
+)
+
+// DumpOptions - configures Dump
+type DumpOptions struct {
+	Format DumpFormat
+	// print empty cells and empty buckets too, the text format collapses runs of the same state.
+	// by default only filled and evacuated cells are printed
+	ShowEmpty bool
+}
+
+// Dumper - implemented by maps which can dump their internal layout.
+//
+//	gomap.New[string, int](10).(gomap.Dumper).Dump(os.Stdout, gomap.DumpOptions{})
+type Dumper interface {
+	Dump(w io.Writer, opts DumpOptions) error
+}
+
+var _ Dumper = (*hmap[string, int])(nil)
+
+type dumpMap struct {
+	Len          int          `json:"len"`
+	B            uint8        `json:"B"`
+	Flags        []string     `json:"flags"`
+	NumEvacuated uint64       `json:"numEvacuated"`
+	Buckets      []dumpBucket `json:"buckets"`
+	OldBuckets   []dumpBucket `json:"oldBuckets,omitempty"`
+}
+
+type dumpBucket struct {
+	Index     int  `json:"index"`
+	Evacuated bool `json:"evacuated,omitempty"`
+	// cells of the bucket and its overflow buckets
+	Chain [][]dumpCell `json:"chain"`
+}
+
+type dumpCell struct {
+	Index   int    `json:"index"`
+	State   string `json:"state"`
+	Tophash uint8  `json:"tophash"`
+	// keys and values are formatted with %v, nil for empty cells
+	Key   *string `json:"key,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+// Dump - writes the bucket array, overflow chains, tophash states,
+// old buckets during growth and the map flags to <w>.
+// It doesn't check for concurrent writes, so it can be used after a panic.
+func (m *hmap[K, V]) Dump(w io.Writer, opts DumpOptions) error {
+	d := dumpMap{
+		Len:          m.len,
+		B:            m.B,
+		Flags:        flagNames(m.allFlags()),
+		NumEvacuated: m.numEvacuated,
+		Buckets:      dumpBuckets(m.buckets, opts.ShowEmpty),
+	}
+	if m.isGrowing() {
+		d.OldBuckets = dumpBuckets(*m.oldbuckets, opts.ShowEmpty)
+	}
+
+	switch opts.Format {
+	case DumpText:
+		return d.writeText(w, m.numOldBuckets())
+	case DumpJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown dump format %d", opts.Format)
+	}
+}
+
+// allFlags - the flags of the map together with the iterator flags
+func (m *hmap[K, V]) allFlags() uint8 {
+	return m.flags | uint8(m.iterators.Load())
+}
+
+func flagNames(flags uint8) []string {
+	names := []string{}
+	for _, f := range []struct {
+		flag uint8
+		name string
+	}{
+		{iterator, "iterator"},
+		{oldIterator, "oldIterator"},
+		{hashWriting, "hashWriting"},
+		{sameSizeGrow, "sameSizeGrow"},
+		{shrinking, "shrinking"},
+	} {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func dumpBuckets[K comparable, V any](buckets []bucket[K, V], showEmpty bool) []dumpBucket {
+	res := make([]dumpBucket, 0, len(buckets))
+	for i := range buckets {
+		d := dumpBucket{Index: i, Evacuated: buckets[i].isEvacuated()}
+		empty := true
+		for b := &buckets[i]; b != nil; b = b.overflow {
+			cells := make([]dumpCell, 0, bucketSize)
+			for j, top := range b.tophash {
+				c := dumpCell{Index: j, State: cellState(top), Tophash: top}
+				// evacuated cells keep their keys and values
+				if top != evacuatedEmpty && !isCellEmpty(top) {
+					key, value := fmt.Sprintf("%v", b.keys[j]), fmt.Sprintf("%v", b.values[j])
+					c.Key, c.Value = &key, &value
+					empty = false
+				} else if !showEmpty {
+					continue
+				}
+				cells = append(cells, c)
+			}
+			d.Chain = append(d.Chain, cells)
+		}
+
+		if showEmpty || !empty || len(d.Chain) > 1 {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func cellState(top uint8) string {
+	switch top {
+	case emptyRest:
+		return "emptyRest"
+	case emptyCell:
+		return "emptyCell"
+	case evacuatedFirst:
+		return "evacuatedFirst"
+	case evacuatedSecond:
+		return "evacuatedSecond"
+	case evacuatedEmpty:
+		return "evacuatedEmpty"
+	default:
+		return "filled"
+	}
+}
+
+func (d dumpMap) writeText(w io.Writer, numOldBuckets uint64) error {
+	buf := strings.Builder{}
+	fmt.Fprintf(&buf, "len=%d B=%d flags=[%s]\n", d.Len, d.B, strings.Join(d.Flags, " "))
+	if d.OldBuckets != nil {
+		fmt.Fprintf(&buf, "growth: %d of %d old buckets evacuated\n", d.NumEvacuated, numOldBuckets)
+	}
+
+	buf.WriteString("buckets:\n")
+	writeBucketsText(&buf, d.Buckets)
+	if d.OldBuckets != nil {
+		buf.WriteString("old buckets:\n")
+		writeBucketsText(&buf, d.OldBuckets)
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func writeBucketsText(buf *strings.Builder, buckets []dumpBucket) {
+	for _, b := range buckets {
+		fmt.Fprintf(buf, "  %d:", b.Index)
+		if b.Evacuated {
+			buf.WriteString(" evacuated")
+		}
+		buf.WriteString("\n")
+
+		for i, cells := range b.Chain {
+			if i > 0 {
+				fmt.Fprintf(buf, "    overflow %d:\n", i)
+			}
+			writeCellsText(buf, cells)
+		}
+	}
+}
+
+// writeCellsText writes a cell per line, runs of cells with the same empty state are collapsed
+func writeCellsText(buf *strings.Builder, cells []dumpCell) {
+	for i := 0; i < len(cells); i++ {
+		c := cells[i]
+		if c.Key != nil {
+			fmt.Fprintf(buf, "      %d: %-15s tophash=%-3d %s: %s\n", c.Index, c.State, c.Tophash, *c.Key, *c.Value)
+			continue
+		}
+
+		last := i
+		for last+1 < len(cells) && cells[last+1].State == c.State && cells[last+1].Index == cells[last].Index+1 {
+			last++
+		}
+		if last == i {
+			fmt.Fprintf(buf, "      %d: %s\n", c.Index, c.State)
+		} else {
+			fmt.Fprintf(buf, "      %d-%d: %s\n", c.Index, cells[last].Index, c.State)
+		}
+		i = last
+	}
+}
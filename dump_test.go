@@ -0,0 +1,108 @@
+package gomap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	// the bucket is chosen by the low bits of a key, tophash is the key (+ minTopHash for keys < minTopHash)
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k) | uint64(k)<<56 })
+	m := NewWithOptions(10, WithHasher[int, int](hasher)).(*hmap[int, int])
+	for i := 0; i < 6; i++ {
+		m.Put(i, i*10)
+	}
+	m.Delete(2)
+
+	dump := func(opts DumpOptions) string {
+		t.Helper()
+		buf := strings.Builder{}
+		err := m.Dump(&buf, opts)
+		isEqual(t, err, nil)
+		return buf.String()
+	}
+
+	isEqual(t, dump(DumpOptions{}), `len=5 B=1 flags=[]
+buckets:
+  0:
+      0: filled          tophash=5   0: 0
+      2: filled          tophash=9   4: 40
+  1:
+      0: filled          tophash=6   1: 10
+      1: filled          tophash=8   3: 30
+      2: filled          tophash=5   5: 50
+`)
+
+	t.Run("growth", func(t *testing.T) {
+		m.Range(func(k, v int) bool { return false })
+		m.moveToNewBuckets(m.B+1, 0)
+		m.evacuate(0)
+
+		isEqual(t, dump(DumpOptions{ShowEmpty: true}), `len=5 B=2 flags=[oldIterator]
+growth: 1 of 2 old buckets evacuated
+buckets:
+  0:
+      0: filled          tophash=5   0: 0
+      1: filled          tophash=9   4: 40
+      2-7: emptyRest
+  1:
+      0-7: emptyRest
+  2:
+      0-7: emptyRest
+  3:
+      0-7: emptyRest
+old buckets:
+  0: evacuated
+      0: evacuatedFirst  tophash=2   0: 0
+      1: evacuatedEmpty
+      2: evacuatedFirst  tophash=2   4: 40
+      3-7: evacuatedEmpty
+  1:
+      0: filled          tophash=6   1: 10
+      1: filled          tophash=8   3: 30
+      2: filled          tophash=5   5: 50
+      3-7: emptyRest
+`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var got dumpMap
+		err := json.Unmarshal([]byte(dump(DumpOptions{Format: DumpJSON})), &got)
+		isEqual(t, err, nil)
+
+		isEqual(t, got.Len, 5)
+		isEqual(t, got.B, uint8(2))
+		isEqual(t, got.Flags, []string{"oldIterator"})
+		isEqual(t, got.NumEvacuated, uint64(1))
+		// empty buckets are skipped
+		isEqual(t, len(got.Buckets), 1)
+		isEqual(t, len(got.OldBuckets), 2)
+		isEqual(t, got.OldBuckets[0].Evacuated, true)
+		isEqual(t, got.OldBuckets[0].Chain[0][1].State, "evacuatedFirst")
+		isEqual(t, *got.OldBuckets[0].Chain[0][1].Key, "4")
+
+		cell := got.Buckets[0].Chain[0][1]
+		isEqual(t, cell.Index, 1)
+		isEqual(t, cell.State, "filled")
+		isEqual(t, cell.Tophash, uint8(9))
+		isEqual(t, *cell.Key, "4")
+		isEqual(t, *cell.Value, "40")
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		m := NewWithOptions(0, WithHasher[int, int](HasherFunc[int](func(k int) uint64 { return 0 })))
+		for i := 0; i < 10; i++ {
+			m.Put(i, i)
+		}
+		buf := strings.Builder{}
+		err := m.(Dumper).Dump(&buf, DumpOptions{})
+		isEqual(t, err, nil)
+		isEqual(t, strings.Contains(buf.String(), "    overflow 1:\n      0: filled          tophash=5   8: 8\n"), true)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := m.Dump(&strings.Builder{}, DumpOptions{Format: 10})
+		isEqual(t, err != nil, true)
+	})
+}
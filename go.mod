@@ -1,11 +1,13 @@
 module github.com/w1kend/go-map
 
-go 1.19
+go 1.23
 
-require github.com/dolthub/maphash v0.0.0-20221220182448-74e1e1ea1577
+require (
+	github.com/dolthub/maphash v0.0.0-20221220182448-74e1e1ea1577
+	github.com/tidwall/hashmap v1.8.0
+)
 
 require (
 	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
-	github.com/tidwall/hashmap v1.8.0 // indirect
 	github.com/zeebo/xxh3 v1.0.2 // indirect
 )
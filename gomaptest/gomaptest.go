@@ -0,0 +1,624 @@
+// Package gomaptest - a conformance test suite for gomap.Hashmap implementations.
+//
+// Every implementation must behave like the built-in map, including the iteration
+// guarantees: an element which isn't deleted during an iteration is produced exactly once,
+// a deleted one isn't produced, and the map can be modified inside the callback.
+// The tests of optional interfaces (gomap.Popper, gomap.Upserter, ...) are skipped
+// for implementations which don't have them.
+//
+//	func TestConformance(t *testing.T) {
+//		gomaptest.Run(t, gomaptest.Factory{
+//			Strings: func(size int) gomap.Hashmap[string, int] { return NewMyMap[string, int](size) },
+//			Floats:  func(size int) gomap.Hashmap[float64, int] { return NewMyMap[float64, int](size) },
+//			Ints:    func(size int) gomap.Hashmap[int, int] { return NewMyMap[int, int](size) },
+//		})
+//	}
+package gomaptest
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	gomap "github.com/w1kend/go-map"
+)
+
+// Factory - constructors of the implementation under test for the key and value types used by the suite.
+// size is the expected number of elements, like in gomap.New.
+type Factory struct {
+	Strings func(size int) gomap.Hashmap[string, int]
+	Floats  func(size int) gomap.Hashmap[float64, int]
+	Ints    func(size int) gomap.Hashmap[int, int]
+}
+
+// Run - runs the whole suite against the implementation created by <f>.
+func Run(t *testing.T, f Factory) {
+	t.Run("get and put", func(t *testing.T) { testGetPut(t, f.Strings) })
+	t.Run("delete", func(t *testing.T) { testDelete(t, f.Strings) })
+	t.Run("pop", func(t *testing.T) { testPop(t, f.Strings) })
+	t.Run("string", func(t *testing.T) { testString(t, f.Strings) })
+	t.Run("range", func(t *testing.T) { testRange(t, f.Strings) })
+	t.Run("range sorted", func(t *testing.T) { testRangeSorted(t, f.Ints) })
+	t.Run("iterators", func(t *testing.T) { testIterators(t, f.Strings) })
+	t.Run("growth during range", func(t *testing.T) { testGrowthDuringRange(t, f.Ints) })
+	t.Run("delete during range", func(t *testing.T) { testDeleteDuringRange(t, f.Ints) })
+	t.Run("NaN keys", func(t *testing.T) { testNaN(t, f.Floats) })
+	t.Run("upsert", func(t *testing.T) { testUpsert(t, f.Strings) })
+	t.Run("clear", func(t *testing.T) { testClear(t, f.Ints) })
+	t.Run("shrink", func(t *testing.T) { testShrink(t, f.Ints) })
+	t.Run("model", func(t *testing.T) { testModel(t, f.Ints) })
+}
+
+func isEqual(t *testing.T, got interface{}, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("result is not equal\ngot:  %+v\nwant: %+v\n", got, want)
+	}
+}
+
+// implements - returns m as the optional interface T, skips the test if m doesn't implement it
+func implements[T any](t *testing.T, m any) T {
+	t.Helper()
+	v, ok := m.(T)
+	if !ok {
+		t.Skipf("%T doesn't implement %s", m, reflect.TypeFor[T]())
+	}
+	return v
+}
+
+func testGetPut(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(8)
+
+	v, ok := m.Get2("123")
+	isEqual(t, ok, false)
+	isEqual(t, v, 0)
+	isEqual(t, m.Get("123"), 0)
+
+	m.Put("key1", 10)
+	isEqual(t, m.Get("key1"), 10)
+
+	m.Put("", 144)
+	isEqual(t, m.Get(""), 144)
+	m.Put(" ", 145)
+	isEqual(t, m.Get(" "), 145)
+
+	m.Put("key1", 20)
+	isEqual(t, m.Get("key1"), 20)
+	isEqual(t, m.Len(), 3)
+
+	// a zero value is still a value
+	m.Put("zero", 0)
+	v, ok = m.Get2("zero")
+	isEqual(t, ok, true)
+	isEqual(t, v, 0)
+	isEqual(t, m.Len(), 4)
+
+	// the map grows from the smallest size
+	m = newMap(0)
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Put(fmt.Sprintf("key_%d", i), i)
+		isEqual(t, m.Len(), i+1)
+	}
+	for i := 0; i < n; i++ {
+		v, ok := m.Get2(fmt.Sprintf("key_%d", i))
+		isEqual(t, ok, true)
+		isEqual(t, v, i)
+	}
+}
+
+func testDelete(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(0)
+
+	m.Delete("123")
+	isEqual(t, m.Len(), 0)
+
+	n := 100
+	for i := 0; i < n; i++ {
+		m.Put(fmt.Sprint(i), i)
+	}
+	for i := 0; i < n; i += 2 {
+		m.Delete(fmt.Sprint(i))
+	}
+	isEqual(t, m.Len(), n/2)
+	for i := 0; i < n; i++ {
+		v, ok := m.Get2(fmt.Sprint(i))
+		isEqual(t, ok, i%2 == 1)
+		if ok {
+			isEqual(t, v, i)
+		}
+	}
+
+	// deleting a missing key is a no-op
+	m.Delete("0")
+	isEqual(t, m.Len(), n/2)
+
+	// deleted keys can be put again
+	m.Put("0", 100)
+	isEqual(t, m.Get("0"), 100)
+	isEqual(t, m.Len(), n/2+1)
+}
+
+func testPop(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(0)
+	p := implements[gomap.Popper[string, int]](t, m)
+	m.Put("1", 1)
+	m.Put("2", 2)
+
+	v, ok := p.Pop("1")
+	isEqual(t, ok, true)
+	isEqual(t, v, 1)
+	v, ok = p.Pop("1")
+	isEqual(t, ok, false)
+	isEqual(t, v, 0)
+	isEqual(t, m.Len(), 1)
+	_, ok = m.Get2("1")
+	isEqual(t, ok, false)
+}
+
+func testString(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(0)
+	isEqual(t, m.String(), "go-map[]")
+
+	m.Put("a", 1)
+	isEqual(t, m.String(), "go-map[a:1]")
+
+	m.Put("b", 2)
+	m.Put("c", 3)
+	str := m.String()
+	if !strings.HasPrefix(str, "go-map[") || !strings.HasSuffix(str, "]") {
+		t.Fatalf("unexpected format: %s", str)
+	}
+	// the order of elements is random
+	pairs := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(str, "go-map["), "]"))
+	sort.Strings(pairs)
+	isEqual(t, pairs, []string{"a:1", "b:2", "c:3"})
+}
+
+func testRange(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(0)
+	m.Range(func(k string, v int) bool {
+		t.Fatalf("empty map produced %s:%d", k, v)
+		return true
+	})
+
+	n := 100
+	want := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		k := fmt.Sprintf("k%d", i)
+		m.Put(k, i*10)
+		want[k] = i * 10
+	}
+
+	got := make(map[string]int, n)
+	m.Range(func(k string, v int) bool {
+		if _, ok := got[k]; ok {
+			t.Fatalf("key %s has been produced twice", k)
+		}
+		got[k] = v
+		return true
+	})
+	isEqual(t, got, want)
+
+	calls := 0
+	m.Range(func(string, int) bool {
+		calls++
+		return calls < 3
+	})
+	isEqual(t, calls, 3)
+}
+
+func testRangeSorted(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	m := newMap(0)
+	sr := implements[gomap.SortedRanger[int, int]](t, m)
+	for _, k := range rand.Perm(100) {
+		m.Put(k, -k)
+	}
+
+	desc := func(a, b int) bool { return a > b }
+	var keys []int
+	sr.RangeSorted(desc, func(k, v int) bool {
+		isEqual(t, v, -k)
+		keys = append(keys, k)
+		// writes don't change the produced elements
+		m.Delete(k - 1)
+		m.Put(k+1000, k)
+		return true
+	})
+	for i, k := range keys {
+		isEqual(t, k, 99-i)
+	}
+	isEqual(t, len(keys), 100)
+
+	calls := 0
+	sr.RangeSorted(desc, func(int, int) bool {
+		calls++
+		return calls < 3
+	})
+	isEqual(t, calls, 3)
+}
+
+func testIterators(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(10)
+	seq := implements[gomap.Sequencer[string, int]](t, m)
+	want := make(map[string]int, 10)
+	for i := 0; i < 10; i++ {
+		k := fmt.Sprintf("k%d", i)
+		m.Put(k, i)
+		want[k] = i
+	}
+
+	isEqual(t, maps.Collect(seq.All()), want)
+	isEqual(t, slices.Sorted(seq.Keys()), slices.Sorted(maps.Keys(want)))
+	isEqual(t, slices.Sorted(seq.Values()), slices.Sorted(maps.Values(want)))
+
+	n := 0
+	for range seq.All() {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	isEqual(t, n, 3)
+
+	empty := implements[gomap.Sequencer[string, int]](t, newMap(0))
+	isEqual(t, len(slices.Collect(empty.Keys())), 0)
+}
+
+func testGrowthDuringRange(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	m := newMap(0)
+	n := 50
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	// every original key puts enough new keys to grow the map a few times during the iteration.
+	// the new keys may be produced too, they don't put anything
+	seen := make(map[int]int, n)
+	next := n
+	m.Range(func(k, v int) bool {
+		isEqual(t, v, k)
+		seen[k]++
+		if k >= n {
+			return true
+		}
+		for i := 0; i < 20; i++ {
+			m.Put(next, next)
+			next++
+		}
+		return true
+	})
+
+	for k, times := range seen {
+		if times != 1 {
+			t.Fatalf("key %d has been produced %d times", k, times)
+		}
+	}
+	for i := 0; i < n; i++ {
+		isEqual(t, seen[i], 1)
+	}
+	isEqual(t, m.Len(), next)
+}
+
+func testDeleteDuringRange(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	m := newMap(0)
+	n := 200
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	seen := make(map[int]bool, n)
+	deleted := make(map[int]bool, n)
+	updated := make(map[int]bool, n)
+	m.Range(func(k, v int) bool {
+		if deleted[k] {
+			t.Fatalf("deleted key %d has been produced", k)
+		}
+		if seen[k] {
+			t.Fatalf("key %d has been produced twice", k)
+		}
+		seen[k] = true
+
+		if updated[k] {
+			isEqual(t, v, -k)
+		} else {
+			isEqual(t, v, k)
+		}
+
+		// updating the current key doesn't create a new element, it mustn't be produced again
+		m.Put(k, v)
+
+		// delete a key which hasn't been produced yet, update another one
+		other := n - 1 - k
+		if other > k && !seen[other] {
+			if other%2 == 0 {
+				m.Delete(other)
+				deleted[other] = true
+			} else {
+				m.Put(other, -other)
+				updated[other] = true
+			}
+		}
+		return true
+	})
+
+	for i := 0; i < n; i++ {
+		isEqual(t, seen[i], !deleted[i])
+	}
+	isEqual(t, m.Len(), n-len(deleted))
+}
+
+func testNaN(t *testing.T, newMap func(size int) gomap.Hashmap[float64, int]) {
+	m := newMap(0)
+	nan := math.NaN()
+
+	// NaN != NaN, so every put adds a new element which can't be found
+	m.Put(nan, 1)
+	m.Put(nan, 2)
+	m.Put(1.5, 3)
+	isEqual(t, m.Len(), 3)
+
+	_, ok := m.Get2(nan)
+	isEqual(t, ok, false)
+	m.Delete(nan)
+	isEqual(t, m.Len(), 3)
+
+	nanValues := func() []int {
+		var values []int
+		m.Range(func(k float64, v int) bool {
+			if k == k {
+				isEqual(t, k, 1.5)
+				isEqual(t, v, 3)
+			} else {
+				values = append(values, v)
+			}
+			return true
+		})
+		slices.Sort(values)
+		return values
+	}
+	isEqual(t, nanValues(), []int{1, 2})
+
+	// NaNs are moved during growth and produced by iterations started before it
+	n := 100
+	want := []int{1, 2}
+	for i := 3; i < n; i++ {
+		m.Put(nan, i)
+		want = append(want, i)
+	}
+	isEqual(t, nanValues(), want)
+
+	var got []int
+	next := 1000
+	m.Range(func(k float64, v int) bool {
+		if k != k && v < 1000 {
+			got = append(got, v)
+		}
+		for i := 0; i < 10; i++ {
+			m.Put(nan, next)
+			next++
+		}
+		return true
+	})
+	slices.Sort(got)
+	isEqual(t, got, want)
+
+	// NaNs can't be deleted one by one, only all together
+	implements[gomap.Clearer](t, m).Clear()
+	isEqual(t, m.Len(), 0)
+	isEqual(t, len(nanValues()), 0)
+}
+
+func testUpsert(t *testing.T, newMap func(size int) gomap.Hashmap[string, int]) {
+	m := newMap(0)
+	u := implements[gomap.Upserter[string, int]](t, m)
+
+	u.Update("a", func(old int, ok bool) (int, bool) {
+		isEqual(t, ok, false)
+		return 0, false
+	})
+	isEqual(t, m.Len(), 0)
+
+	u.Update("a", func(old int, ok bool) (int, bool) { return old + 1, true })
+	u.Update("a", func(old int, ok bool) (int, bool) {
+		isEqual(t, ok, true)
+		return old + 1, true
+	})
+	isEqual(t, m.Get("a"), 2)
+
+	v, loaded := u.GetOrPut("a", 10)
+	isEqual(t, v, 2)
+	isEqual(t, loaded, true)
+	v, loaded = u.GetOrPut("b", 10)
+	isEqual(t, v, 10)
+	isEqual(t, loaded, false)
+
+	v, loaded = u.Swap("b", 20)
+	isEqual(t, v, 10)
+	isEqual(t, loaded, true)
+	v, loaded = u.Swap("c", 30)
+	isEqual(t, v, 0)
+	isEqual(t, loaded, false)
+	isEqual(t, m.Get("c"), 30)
+
+	v, ok := u.Compute("c", func(old int, ok bool) (int, bool) { return old * 2, true })
+	isEqual(t, v, 60)
+	isEqual(t, ok, true)
+	v, ok = u.Compute("c", func(old int, ok bool) (int, bool) { return 0, false })
+	isEqual(t, v, 0)
+	isEqual(t, ok, false)
+	_, ok = m.Get2("c")
+	isEqual(t, ok, false)
+	isEqual(t, m.Len(), 2)
+}
+
+func testClear(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	m := newMap(0)
+	c := implements[gomap.Clearer](t, m)
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	c.Clear()
+	isEqual(t, m.Len(), 0)
+	isEqual(t, m.String(), "go-map[]")
+	for i := 0; i < n; i++ {
+		_, ok := m.Get2(i)
+		isEqual(t, ok, false)
+	}
+
+	for i := 0; i < n; i++ {
+		m.Put(i, -i)
+	}
+	isEqual(t, m.Get(n-1), 1-n)
+
+	// an iteration doesn't produce anything after the map has been cleared
+	calls := 0
+	m.Range(func(k, v int) bool {
+		calls++
+		c.Clear()
+		return true
+	})
+	isEqual(t, calls, 1)
+
+	m.Put(1, 1)
+	c.Reset(10)
+	isEqual(t, m.Len(), 0)
+	_, ok := m.Get2(1)
+	isEqual(t, ok, false)
+	m.Put(1, 1)
+	isEqual(t, m.Get(1), 1)
+}
+
+func testShrink(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	m := newMap(0)
+	s := implements[gomap.Shrinker](t, m)
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+	for i := 10; i < n; i++ {
+		m.Delete(i)
+	}
+
+	s.Shrink()
+	s.Compact()
+	isEqual(t, m.Len(), 10)
+	for i := 0; i < n; i++ {
+		v, ok := m.Get2(i)
+		isEqual(t, ok, i < 10)
+		if ok {
+			isEqual(t, v, i)
+		}
+	}
+
+	for i := 10; i < n; i++ {
+		m.Put(i, i)
+	}
+	isEqual(t, m.Len(), n)
+}
+
+// testModel - applies random operations to the map and to a built-in map and compares results.
+// operations of the optional interfaces which the map doesn't implement are skipped.
+func testModel(t *testing.T, newMap func(size int) gomap.Hashmap[int, int]) {
+	for seed := int64(0); seed < 10; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		m := newMap(r.Intn(50))
+		std := make(map[int]int)
+
+		popper, canPop := m.(gomap.Popper[int, int])
+		upserter, canUpsert := m.(gomap.Upserter[int, int])
+		clearer, canClear := m.(gomap.Clearer)
+		shrinker, canShrink := m.(gomap.Shrinker)
+
+		// a small key space makes deletes of existing keys likely
+		keys := 1 + r.Intn(500)
+		for op := 0; op < 5000; op++ {
+			k := r.Intn(keys)
+			switch r.Intn(10) {
+			case 0, 1, 2:
+				m.Put(k, op)
+				std[k] = op
+			case 3:
+				m.Delete(k)
+				delete(std, k)
+			case 4:
+				if !canPop {
+					break
+				}
+				v, ok := popper.Pop(k)
+				want, wantOk := std[k]
+				isEqual(t, ok, wantOk)
+				isEqual(t, v, want)
+				delete(std, k)
+			case 5:
+				if !canUpsert {
+					break
+				}
+				upserter.Compute(k, func(old int, ok bool) (int, bool) { return old + 1, old%3 != 2 })
+				if v := std[k]; v%3 != 2 {
+					std[k] = v + 1
+				} else {
+					delete(std, k)
+				}
+			case 6:
+				if !canUpsert {
+					break
+				}
+				v, loaded := upserter.GetOrPut(k, op)
+				want, wantLoaded := std[k]
+				if !wantLoaded {
+					want = op
+					std[k] = op
+				}
+				isEqual(t, loaded, wantLoaded)
+				isEqual(t, v, want)
+			case 7:
+				v, ok := m.Get2(k)
+				want, wantOk := std[k]
+				isEqual(t, ok, wantOk)
+				isEqual(t, v, want)
+			case 8:
+				// a partial iteration
+				limit := r.Intn(len(std) + 1)
+				seen := make(map[int]bool, limit)
+				m.Range(func(k, v int) bool {
+					if seen[k] {
+						t.Fatalf("key %d has been produced twice", k)
+					}
+					seen[k] = true
+					want, ok := std[k]
+					isEqual(t, ok, true)
+					isEqual(t, v, want)
+					return len(seen) < limit
+				})
+			case 9:
+				switch r.Intn(50) {
+				case 0:
+					if canClear {
+						clearer.Clear()
+						clear(std)
+					}
+				case 1:
+					if canShrink {
+						shrinker.Shrink()
+					}
+				}
+			}
+			isEqual(t, m.Len(), len(std))
+		}
+		got := make(map[int]int, m.Len())
+		m.Range(func(k, v int) bool {
+			got[k] = v
+			return true
+		})
+		isEqual(t, got, std)
+	}
+}
@@ -0,0 +1,21 @@
+package gomap
+
+import "github.com/dolthub/maphash"
+
+// Hasher - hash function for map keys.
+// Keys which are equal by == must have equal hashes.
+type Hasher[K comparable] interface {
+	Hash(key K) uint64
+}
+
+// HasherFunc - an adapter to use an ordinary function as a Hasher.
+type HasherFunc[K comparable] func(key K) uint64
+
+func (f HasherFunc[K]) Hash(key K) uint64 {
+	return f(key)
+}
+
+// defaultHasher - Go's runtime hasher with a random seed
+func defaultHasher[K comparable]() Hasher[K] {
+	return maphash.NewHasher[K]()
+}
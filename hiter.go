@@ -11,15 +11,23 @@ type hiter[K comparable, V any] struct {
 	key           *K
 	elem          *V
 	m             *hmap[K, V]
-	buckets       *[]bucket[K, V] // bucket ptr at hash_iter initialization time
-	currBktPtr    *bucket[K, V]   // current bucket
-	startBucket   uint64          // bucket iteration started at
-	offset        uint8           // intra-bucket offset to start from during iteration (should be big enough to hold bucketCnt-1)
-	wrapped       bool            // already wrapped around from end of bucket array to beginning
+	buckets       []bucket[K, V] // buckets at hash_iter initialization time
+	currBktPtr    *bucket[K, V]  // current bucket
+	startBucket   uint64         // bucket iteration started at
+	offset        uint8          // intra-bucket offset to start from during iteration (should be big enough to hold bucketCnt-1)
+	wrapped       bool           // already wrapped around from end of bucket array to beginning
 	B             uint8
 	i             uint8
 	currBucketNum uint64
 	checkBucket   uint64
+	pairBktPtr    *bucket[K, V] // the second old bucket of the current one during shrinking
+	headBktPtr    *bucket[K, V] // the first bucket of the chain being iterated
+
+	// the cell of the current element: the first bucket of its chain, the bucket and the index.
+	// cellBkt is nil if the cell is left in an evacuated bucket (NaN keys can't be looked up)
+	cellHead *bucket[K, V]
+	cellBkt  *bucket[K, V]
+	cellIdx  int
 }
 
 func iterInit[K comparable, V any](m *hmap[K, V]) *hiter[K, V] {
@@ -31,24 +39,49 @@ func iterInit[K comparable, V any](m *hmap[K, V]) *hiter[K, V] {
 
 	h.m = m
 	h.B = m.B
-	h.buckets = &m.buckets
-	h.startBucket = rand.Uint64() & bucketMask(m.B) // pick random bucket
-	// choose offset to start from inside a bucket
-	h.offset = uint8(uint8(h.startBucket) >> h.B & (bucketSize - 1))
+	h.buckets = m.buckets
+	// a deterministic iteration starts at the first cell of the first bucket
+	if !m.deterministic {
+		h.startBucket = rand.Uint64() & bucketMask(m.B) // pick random bucket
+		// choose offset to start from inside a bucket
+		h.offset = uint8(uint8(h.startBucket) >> h.B & (bucketSize - 1))
+	}
 	h.currBucketNum = h.startBucket
 
-	h.m.flags |= iterator | oldIterator // set iterators flags
+	// set iterators flags. check them first, so concurrent iterations
+	// don't contend once the flags are set
+	if m.iterators.Load() != iterator|oldIterator {
+		m.iterators.Or(iterator | oldIterator)
+	}
 	h.next()
 
 	return &h
 }
 
 func (it *hiter[K, V]) next() {
+	if it.m.guard != nil {
+		it.m.guard.startReading("concurrent map iteration and map write")
+		defer it.m.guard.finishReading()
+	}
+	if it.m.flags&hashWriting != 0 {
+		panic("concurrent map iteration and map write")
+	}
+
 	b := it.currBktPtr
 	bucketNum := it.currBucketNum
 	i := it.i
 	checkBucket := it.checkBucket
+	pair := it.pairBktPtr
+	head := it.headBktPtr
 next:
+	// during shrinking two old buckets are merged into the current one,
+	// iterate through the second one after the first
+	if b == nil && pair != nil {
+		b, pair = pair, nil
+		head = b
+		i = 0
+	}
+
 	// choose bucket
 	if b == nil {
 		if bucketNum == it.startBucket && it.wrapped {
@@ -60,7 +93,7 @@ next:
 
 		// check old buckets if gwoth is not done
 		// skip it if growth started during iteration
-		if it.m.isGrowing() && it.B == it.m.B {
+		if it.m.isGrowing() && it.isCurrentBuckets() {
 			// runtime/map.go:890
 			// Iterator was started in the middle of a grow, and the grow isn't done yet.
 			// If the bucket we're looking at hasn't been filled in yet (i.e. the old
@@ -69,16 +102,29 @@ next:
 			oldBucketNum := bucketNum & it.m.oldBucketMask()
 			b = &(*it.m.oldbuckets)[oldBucketNum]
 			if !b.isEvacuated() {
-				checkBucket = bucketNum
+				// only a bigger growth splits the old bucket, the kind is taken now
+				// as the growth can finish and a shrink can start before the bucket is done
+				checkBucket = noCheck
+				if it.m.growsBigger() {
+					checkBucket = bucketNum
+				}
+				if it.m.isShrinking() {
+					// both old buckets are evacuated together, so the second one isn't evacuated either
+					pair = &(*it.m.oldbuckets)[bucketNum+bucketsNum(it.m.B)]
+				}
 			} else {
 				checkBucket = noCheck
-				b = &it.m.buckets[bucketNum]
+				b = &it.buckets[bucketNum]
 			}
 		} else {
+			// the buckets the iteration was started with.
+			// if the map has grown since then the values have been marked as evacuated
+			// and they are looked up in the current buckets below
 			checkBucket = noCheck
-			b = &it.m.buckets[bucketNum]
+			b = &it.buckets[bucketNum]
 		}
 
+		head = b
 		bucketNum++
 		if bucketNum == bucketsNum(it.B) {
 			bucketNum = 0
@@ -99,7 +145,7 @@ next:
 		key := &b.keys[offI]
 		elem := &b.values[offI]
 
-		if checkBucket != noCheck && !it.m.sameSizeGrow() {
+		if checkBucket != noCheck {
 			// runtime/map.go:925
 			// Special case: iterator was started during a grow to a larger size
 			// and the grow is not done yet. We're working on a bucket whose
@@ -109,7 +155,7 @@ next:
 			// to the other new bucket (each oldbucket expands to two
 			// buckets during a grow).
 
-			if key == key {
+			if *key == *key {
 				hash := it.m.hasher.Hash(*key)
 				if hash&bucketMask(it.B) != checkBucket {
 					continue
@@ -129,10 +175,16 @@ next:
 			}
 		}
 
-		if (top != evacuatedFirst && top != evacuatedSecond) || key != key {
+		if top != evacuatedFirst && top != evacuatedSecond {
 			// This is the golden data, we can return it.
 			it.key = key
 			it.elem = elem
+			it.cellHead, it.cellBkt, it.cellIdx = head, b, int(offI)
+		} else if *key != *key {
+			// NaNs can't be looked up, the value is returned from the evacuated bucket
+			it.key = key
+			it.elem = elem
+			it.cellHead, it.cellBkt = nil, nil
 		} else {
 			// The hash table has grown since the iterator was started.
 			// The golden data for this key is now somewhere else.
@@ -142,12 +194,13 @@ next:
 			// has been deleted, updated, or deleted and reinserted.
 			// NOTE: we need to regrab the key as it has potentially been
 			// updated to an equal() but not identical key (e.g. +0.0 vs -0.0).
-			re, ok := it.m.Get2(*key) // todo: add getK method
+			cellHead, cellBkt, cellIdx, ok := it.m.lookup(*key, it.m.hasher.Hash(*key))
 			if !ok {
 				continue // key has been deleted
 			}
-			it.key = key
-			it.elem = &re
+			it.key = &cellBkt.keys[cellIdx]
+			it.elem = &cellBkt.values[cellIdx]
+			it.cellHead, it.cellBkt, it.cellIdx = cellHead, cellBkt, cellIdx
 		}
 
 		// update iteration state and return
@@ -157,6 +210,8 @@ next:
 		}
 		it.i = i + 1
 		it.checkBucket = checkBucket
+		it.pairBktPtr = pair
+		it.headBktPtr = head
 		return
 	}
 
@@ -165,3 +220,9 @@ next:
 	i = 0
 	goto next
 }
+
+// isCurrentBuckets reports whether the iteration was started on the current main bucket array.
+// the runtime compares B for that, but B stays the same during a same size growth.
+func (it *hiter[K, V]) isCurrentBuckets() bool {
+	return &it.buckets[0] == &it.m.buckets[0]
+}
@@ -0,0 +1,64 @@
+package gomap
+
+// Locator - implemented by maps which can report where a key is placed.
+//
+//	hash, tophash, bucket := gomap.New[string, int](10).(gomap.Locator[string]).Locate("key")
+type Locator[K comparable] interface {
+	// returns the hash of the key, its tophash and the bucket of the main array for it
+	Locate(key K) (hash uint64, tophash uint8, bucket uint64)
+}
+
+// GrowthController - debug hooks to drive the incremental growth of a map created by New by hand.
+// They are meant for tests, tools and teaching.
+type GrowthController interface {
+	// starts growth to the double size or to the same size.
+	// an unfinished growth is finished first
+	ForceGrow(sameSize bool)
+	// evacuates up to <n> old buckets in order, like every write does
+	EvacuateStep(n int)
+	// evacuates all the remaining old buckets
+	FinishGrowth()
+	IsGrowing() bool
+}
+
+var (
+	_ Locator[string]  = (*hmap[string, int])(nil)
+	_ GrowthController = (*hmap[string, int])(nil)
+)
+
+func (m *hmap[K, V]) Locate(key K) (hash uint64, tophash uint8, bucket uint64) {
+	return m.locateBucket(key)
+}
+
+func (m *hmap[K, V]) ForceGrow(sameSize bool) {
+	m.startWriting()
+
+	m.finishGrowth()
+	if sameSize {
+		m.moveToNewBuckets(m.B, sameSizeGrow)
+	} else {
+		m.moveToNewBuckets(m.B+1, 0)
+	}
+
+	m.finishWriting()
+}
+
+func (m *hmap[K, V]) EvacuateStep(n int) {
+	m.startWriting()
+
+	for ; n > 0 && m.isGrowing(); n-- {
+		m.evacuate(m.numEvacuated)
+	}
+
+	m.finishWriting()
+}
+
+func (m *hmap[K, V]) FinishGrowth() {
+	m.startWriting()
+	m.finishGrowth()
+	m.finishWriting()
+}
+
+func (m *hmap[K, V]) IsGrowing() bool {
+	return m.isGrowing()
+}
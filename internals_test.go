@@ -0,0 +1,179 @@
+package gomap
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k) | uint64(k)<<56 })
+	m := NewWithOptions(20, WithHasher[int, int](hasher)).(*hmap[int, int])
+
+	hash, tophash, bucket := m.Locate(13)
+	isEqual(t, hash, uint64(13)|13<<56)
+	isEqual(t, tophash, uint8(13))
+	isEqual(t, bucket, uint64(1)) // B=2
+}
+
+func TestForceGrow(t *testing.T) {
+	m := New[int, int](20).(*hmap[int, int])
+	for i := 0; i < 10; i++ {
+		m.Put(i, i)
+	}
+
+	m.ForceGrow(false)
+	isEqual(t, m.B, uint8(3))
+	isEqual(t, m.isGrowing(), true)
+	mustValidate(t, m)
+
+	m.EvacuateStep(2)
+	isEqual(t, m.numEvacuated, uint64(2))
+	mustValidate(t, m)
+
+	// the unfinished growth is finished first
+	m.ForceGrow(true)
+	isEqual(t, m.B, uint8(3))
+	isEqual(t, m.sameSizeGrow(), true)
+	mustValidate(t, m)
+
+	m.EvacuateStep(100)
+	isEqual(t, m.isGrowing(), false)
+	mustValidate(t, m)
+	for i := 0; i < 10; i++ {
+		isEqual(t, m.Get(i), i)
+	}
+}
+
+// TestGrowthStates - stops the growth after every evacuated old bucket
+// and checks reads, iterations and writes in that state
+func TestGrowthStates(t *testing.T) {
+	const n = 50
+
+	for _, kind := range []struct {
+		name string
+		grow func(m *hmap[int, int])
+	}{
+		{"bigger", func(m *hmap[int, int]) { m.ForceGrow(false) }},
+		{"same size", func(m *hmap[int, int]) { m.ForceGrow(true) }},
+		{"shrinking", func(m *hmap[int, int]) { m.startShrink() }},
+	} {
+		newMap := func(t *testing.T, step uint64) (*hmap[int, int], map[int]int) {
+			m := New[int, int](100).(*hmap[int, int])
+			want := map[int]int{}
+			for i := 0; i < n; i++ {
+				m.Put(i, i)
+				want[i] = i
+			}
+
+			kind.grow(m)
+			m.EvacuateStep(int(step))
+			isEqual(t, m.IsGrowing(), true)
+			isEqual(t, m.numEvacuated, step)
+			mustValidate(t, m)
+			return m, want
+		}
+
+		// shrinking evacuates two old buckets at once
+		steps := uint64(0)
+		for m, _ := newMap(t, 0); m.IsGrowing(); steps++ {
+			m.EvacuateStep(1)
+		}
+
+		for step := uint64(0); step < steps; step++ {
+			t.Run(fmt.Sprintf("%s/%d evacuated/iterate", kind.name, step), func(t *testing.T) {
+				m, want := newMap(t, step)
+				checkRange(t, m, want, false)
+			})
+
+			t.Run(fmt.Sprintf("%s/%d evacuated/iterate while evacuating", kind.name, step), func(t *testing.T) {
+				m, want := newMap(t, step)
+				checkRange(t, m, want, true)
+				mustValidate(t, m)
+			})
+
+			// deletes finish the growth and start a shrink in the middle of the iteration
+			t.Run(fmt.Sprintf("%s/%d evacuated/iterate while deleting", kind.name, step), func(t *testing.T) {
+				m, want := newMap(t, step)
+				got := map[int]int{}
+				m.Range(func(k, v int) bool {
+					if _, ok := got[k]; ok {
+						t.Fatalf("key %d is returned twice", k)
+					}
+					got[k] = v
+					if k%3 == 0 {
+						m.Delete(k)
+					}
+					return true
+				})
+				isEqual(t, got, want)
+
+				for k := range want {
+					if k%3 == 0 {
+						delete(want, k)
+					}
+				}
+				mustValidate(t, m)
+				checkContents(t, m, want)
+			})
+
+			t.Run(fmt.Sprintf("%s/%d evacuated/delete", kind.name, step), func(t *testing.T) {
+				m, want := newMap(t, step)
+				for i := 0; i < n; i += 2 {
+					m.Delete(i)
+					delete(want, i)
+				}
+				mustValidate(t, m)
+				checkContents(t, m, want)
+
+				m.FinishGrowth()
+				isEqual(t, m.IsGrowing(), false)
+				mustValidate(t, m)
+				checkContents(t, m, want)
+			})
+
+			t.Run(fmt.Sprintf("%s/%d evacuated/put", kind.name, step), func(t *testing.T) {
+				m, want := newMap(t, step)
+				for i := 0; i < 2*n; i++ {
+					m.Put(i, -i)
+					want[i] = -i
+				}
+				mustValidate(t, m)
+				checkContents(t, m, want)
+
+				m.FinishGrowth()
+				mustValidate(t, m)
+				checkContents(t, m, want)
+			})
+		}
+	}
+}
+
+func checkContents(t *testing.T, m *hmap[int, int], want map[int]int) {
+	t.Helper()
+	isEqual(t, m.Len(), len(want))
+	for i := 0; i < 200; i++ {
+		v, ok := m.Get2(i)
+		wantV, wantOk := want[i]
+		if v != wantV || ok != wantOk {
+			t.Fatalf("Get2(%d) = %d, %t, want %d, %t", i, v, ok, wantV, wantOk)
+		}
+	}
+}
+
+// checkRange - checks that Range returns every element once.
+// with <evacuate> it evacuates an old bucket after every element
+func checkRange(t *testing.T, m *hmap[int, int], want map[int]int, evacuate bool) {
+	t.Helper()
+	got := map[int]int{}
+	m.Range(func(k, v int) bool {
+		if _, ok := got[k]; ok {
+			t.Fatalf("key %d is returned twice", k)
+		}
+		got[k] = v
+		if evacuate {
+			m.EvacuateStep(1)
+		}
+		return true
+	})
+	isEqual(t, got, want)
+}
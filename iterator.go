@@ -0,0 +1,145 @@
+package gomap
+
+// Iterator - an external iterator over a map created by New. It can be paused,
+// passed around and resumed later, the map can be changed in between.
+// It gives the same guarantees as Range: an element which isn't deleted
+// during the iteration is produced exactly once, a deleted one isn't produced,
+// an element added during the iteration may or may not be produced.
+//
+//	it := m.(gomap.Iterable[string, int]).Iter()
+//	defer it.Close()
+//	for it.Next() {
+//		if it.Value() < 0 {
+//			it.Delete()
+//		}
+//	}
+type Iterator[K comparable, V any] struct {
+	m      *hmap[K, V]
+	h      *hiter[K, V] // nil before the first Next and after Close
+	done   bool
+	hasCur bool // there is a current element
+
+	key   K
+	value V
+}
+
+// Iterable - implemented by maps which provide an external iterator.
+type Iterable[K comparable, V any] interface {
+	Iter() *Iterator[K, V]
+}
+
+var _ Iterable[string, int] = (*hmap[string, int])(nil)
+
+// Iter - returns an iterator positioned before the first element
+func (m *hmap[K, V]) Iter() *Iterator[K, V] {
+	return &Iterator[K, V]{m: m}
+}
+
+// Next - advances to the next element. returns false when there are no more elements.
+func (it *Iterator[K, V]) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if it.h == nil {
+		it.h = iterInit(it.m)
+	} else {
+		it.h.next()
+	}
+
+	if it.h.key == nil || it.h.elem == nil {
+		it.Close()
+		return false
+	}
+
+	it.key, it.value, it.hasCur = *it.h.key, *it.h.elem, true
+	return true
+}
+
+// Key - returns the key of the current element
+func (it *Iterator[K, V]) Key() K {
+	it.mustHaveCurrent("Key")
+	return it.key
+}
+
+// Value - returns the value of the current element as it was when the iterator reached it
+// or set by SetValue
+func (it *Iterator[K, V]) Value() V {
+	it.mustHaveCurrent("Value")
+	return it.value
+}
+
+// SetValue - changes the value of the current element in its cell.
+// If the element has been moved by growth since Next, it's looked up by the key.
+// If it has been deleted, SetValue does nothing and Value returns the old value:
+// a deleted element isn't put back. NaN keys can't be looked up,
+// so values of NaN keys moved by growth aren't changed either.
+func (it *Iterator[K, V]) SetValue(value V) {
+	it.mustHaveCurrent("SetValue")
+	m := it.m
+	m.startWriting()
+
+	if _, bkt, i := it.cell(); bkt != nil {
+		bkt.values[i] = value
+		it.value = value
+	}
+
+	m.finishWriting()
+}
+
+// Delete - deletes the current element from its cell. Key and Value still return it until Next.
+// If the element has been moved by growth since Next, it's looked up by the key.
+func (it *Iterator[K, V]) Delete() {
+	it.mustHaveCurrent("Delete")
+	m := it.m
+	m.startWriting()
+
+	if head, bkt, i := it.cell(); bkt != nil {
+		head.deleteAt(bkt, i)
+		m.deleted()
+	}
+
+	m.finishWriting()
+}
+
+// cell - returns the cell of the current element, bkt is nil if the element has been deleted.
+// the map must be marked as being written.
+func (it *Iterator[K, V]) cell() (head, bkt *bucket[K, V], i int) {
+	head, bkt, i = it.h.cellHead, it.h.cellBkt, it.h.cellIdx
+	if bkt != nil && (bkt.tophash[i] < minTopHash || !sameKey(bkt.keys[i], it.key)) {
+		// the cell has been evacuated, emptied or reused by another key
+		bkt = nil
+	}
+	if bkt == nil && it.key == it.key {
+		var found bool
+		if head, bkt, i, found = it.m.lookup(it.key, it.m.hasher.Hash(it.key)); !found {
+			bkt = nil
+		}
+	}
+	return head, bkt, i
+}
+
+// Reset - moves the iterator before the first element,
+// the next iteration starts anew and sees the current state of the map
+func (it *Iterator[K, V]) Reset() {
+	*it = Iterator[K, V]{m: it.m}
+}
+
+// Close - stops the iteration and releases the buckets it holds. Next returns false after it.
+func (it *Iterator[K, V]) Close() {
+	it.h = nil
+	it.done = true
+	it.hasCur = false
+	it.key, it.value = *new(K), *new(V)
+}
+
+// sameKey - reports whether the keys are equal, NaNs are considered equal
+func sameKey[K comparable](a, b K) bool {
+	return a == b || (a != a && b != b)
+}
+
+func (it *Iterator[K, V]) mustHaveCurrent(method string) {
+	if !it.hasCur {
+		panic("gomap: Iterator." + method + " called without a current element")
+	}
+}
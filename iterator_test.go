@@ -0,0 +1,225 @@
+package gomap
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	t.Run("iterate", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		it := m.Iter()
+		isEqual(t, it.Next(), false)
+
+		want := map[int]int{}
+		for i := 0; i < 100; i++ {
+			m.Put(i, -i)
+			want[i] = -i
+		}
+
+		got := map[int]int{}
+		for it = m.Iter(); it.Next(); {
+			if _, ok := got[it.Key()]; ok {
+				t.Fatalf("key %d is produced twice", it.Key())
+			}
+			got[it.Key()] = it.Value()
+		}
+		isEqual(t, got, want)
+		// an exhausted iterator stays exhausted
+		isEqual(t, it.Next(), false)
+	})
+
+	t.Run("set value and delete", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+
+		for it := m.Iter(); it.Next(); {
+			if it.Key()%2 == 0 {
+				it.Delete()
+				isEqual(t, it.Value(), it.Key())
+				continue
+			}
+			it.SetValue(-it.Key())
+			isEqual(t, it.Value(), -it.Key())
+		}
+
+		isEqual(t, m.Len(), 50)
+		for i := 1; i < 100; i += 2 {
+			isEqual(t, m.Get(i), -i)
+		}
+		mustValidate(t, m)
+	})
+
+	t.Run("pause and resume during growth", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 50; i++ {
+			m.Put(i, i)
+		}
+
+		seen := map[int]int{}
+		deleted := map[int]bool{}
+		it := m.Iter()
+		for next := 1000; it.Next(); next++ {
+			k := it.Key()
+			if _, ok := seen[k]; ok {
+				t.Fatalf("key %d is produced twice", k)
+			}
+			if deleted[k] {
+				t.Fatalf("deleted key %d is produced", k)
+			}
+			seen[k] = it.Value()
+
+			// the map grows while the iterator is paused
+			switch {
+			case len(seen) == 5:
+				m.ForceGrow(false)
+			case len(seen) == 20:
+				m.ForceGrow(true)
+				m.EvacuateStep(3)
+			}
+			m.Put(next, next)
+			// delete an element which hasn't been produced yet
+			for d := 0; d < 50; d++ {
+				if _, ok := seen[d]; !ok && !deleted[d] {
+					m.Delete(d)
+					deleted[d] = true
+					break
+				}
+			}
+		}
+
+		// every original element is either produced or deleted
+		for i := 0; i < 50; i++ {
+			_, ok := seen[i]
+			if ok == deleted[i] {
+				t.Fatalf("key %d: produced %t, deleted %t", i, ok, deleted[i])
+			}
+		}
+		mustValidate(t, m)
+	})
+
+	t.Run("reset and close", func(t *testing.T) {
+		m := New[string, int](0).(*hmap[string, int])
+		m.Put("a", 1)
+		m.Put("b", 2)
+
+		it := m.Iter()
+		isEqual(t, it.Next(), true)
+		it.Reset()
+		m.Put("c", 3)
+
+		n := 0
+		for it.Next() {
+			n++
+		}
+		isEqual(t, n, 3)
+
+		it.Reset()
+		isEqual(t, it.Next(), true)
+		it.Close()
+		isEqual(t, it.Next(), false)
+		isEqual(t, it.h, (*hiter[string, int])(nil))
+	})
+
+	t.Run("NaN keys", func(t *testing.T) {
+		m := New[float64, int](0).(*hmap[float64, int])
+		m.Put(math.NaN(), 1)
+		m.Put(math.NaN(), 2)
+
+		n := 0
+		for it := m.Iter(); it.Next(); n++ {
+			it.SetValue(10)
+			isEqual(t, it.Value(), 10)
+			it.Delete()
+		}
+		isEqual(t, n, 2)
+		// NaN keys can't be looked up, they are deleted in their cells
+		isEqual(t, m.Len(), 0)
+		mustValidate(t, m)
+	})
+
+	t.Run("set value of a deleted element", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		m.Put(1, 1)
+		m.Put(2, 2)
+
+		it := m.Iter()
+		isEqual(t, it.Next(), true)
+		k := it.Key()
+		m.Delete(k)
+
+		// the element isn't put back
+		it.SetValue(10)
+		isEqual(t, it.Value(), k)
+		isEqual(t, m.Len(), 1)
+		_, ok := m.Get2(k)
+		isEqual(t, ok, false)
+		mustValidate(t, m)
+	})
+
+	t.Run("set value of a moved element", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 20; i++ {
+			m.Put(i, i)
+		}
+
+		it := m.Iter()
+		isEqual(t, it.Next(), true)
+		k := it.Key()
+		m.ForceGrow(false)
+		m.FinishGrowth()
+
+		it.SetValue(-1)
+		isEqual(t, it.Value(), -1)
+		isEqual(t, m.Get(k), -1)
+		isEqual(t, m.Len(), 20)
+		mustValidate(t, m)
+	})
+
+	t.Run("delete a deleted or moved element", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 20; i++ {
+			m.Put(i, i)
+		}
+
+		it := m.Iter()
+		isEqual(t, it.Next(), true)
+		k := it.Key()
+		m.Delete(k)
+		// the element isn't counted twice
+		it.Delete()
+		isEqual(t, m.Len(), 19)
+
+		isEqual(t, it.Next(), true)
+		k = it.Key()
+		m.ForceGrow(false)
+		m.FinishGrowth()
+		it.Delete()
+		isEqual(t, m.Len(), 18)
+		_, ok := m.Get2(k)
+		isEqual(t, ok, false)
+		mustValidate(t, m)
+	})
+
+	t.Run("no current element", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		m.Put(1, 1)
+		it := m.Iter()
+
+		for name, f := range map[string]func(){
+			"Key":      func() { it.Key() },
+			"Value":    func() { it.Value() },
+			"SetValue": func() { it.SetValue(1) },
+			"Delete":   func() { it.Delete() },
+		} {
+			func() {
+				defer func() {
+					isEqual(t, recover(), "gomap: Iterator."+name+" called without a current element")
+				}()
+				f()
+			}()
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package gomap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ json.Marshaler   = (*hmap[string, int])(nil)
+	_ json.Unmarshaler = (*hmap[string, int])(nil)
+
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// MarshalJSON - encodes the map as a JSON object. Keys follow the rules of encoding/json for std maps:
+// string keys are used as is, encoding.TextMarshaler keys are marshaled, integer keys are formatted.
+// Keys are written in the iteration order, or sorted with WithSortedJSON.
+func (m *hmap[K, V]) MarshalJSON() ([]byte, error) {
+	type entry struct {
+		key   string
+		value []byte
+	}
+	entries := make([]entry, 0, m.Len())
+
+	var err error
+	m.Range(func(k K, v V) bool {
+		e := entry{}
+		if e.key, err = jsonKey(k); err != nil {
+			return false
+		}
+		e.value, err = json.Marshal(v)
+		entries = append(entries, e)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if m.sortedJSON {
+		slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(e.value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON - puts the elements of a JSON object into the map.
+// Like encoding/json does for std maps, the existing elements are kept, null doesn't change the map.
+// The map isn't changed if any key or value can't be decoded.
+func (m *hmap[K, V]) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	keys, values := make([]K, 0, len(raw)), make([]V, 0, len(raw))
+	for s, rawValue := range raw {
+		key, err := parseJSONKey[K](s)
+		if err != nil {
+			return err
+		}
+
+		var value V
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			return err
+		}
+		keys, values = append(keys, key), append(values, value)
+	}
+
+	for i := range keys {
+		m.Put(keys[i], values[i])
+	}
+	return nil
+}
+
+// jsonKey - encoding/json resolveKeyName for map keys
+func jsonKey[K comparable](k K) (string, error) {
+	v := reflect.ValueOf(&k).Elem()
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return "", nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: v.Type()}
+}
+
+// parseJSONKey - decodes a key of a JSON object like encoding/json does for std maps
+func parseJSONKey[K comparable](s string) (K, error) {
+	var k K
+	v := reflect.ValueOf(&k).Elem()
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		err := u.UnmarshalText([]byte(s))
+		return k, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return k, &json.UnmarshalTypeError{Value: "number " + s, Type: v.Type()}
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return k, &json.UnmarshalTypeError{Value: "number " + s, Type: v.Type()}
+		}
+		v.SetUint(n)
+	default:
+		return k, &json.UnmarshalTypeError{Value: "object", Type: v.Type()}
+	}
+	return k, nil
+}
@@ -0,0 +1,127 @@
+package gomap
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	t.Run("sorted like std maps", func(t *testing.T) {
+		m := NewWithOptions(0, WithSortedJSON[int8, []string]())
+		std := map[int8][]string{}
+		for i := int8(-50); i < 50; i += 7 {
+			m.Put(i, []string{"<v>", strings.Repeat("x", int(i+50)%3)})
+			std[i] = []string{"<v>", strings.Repeat("x", int(i+50)%3)}
+		}
+
+		got, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(std)
+		isEqual(t, string(got), string(want))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		m := New[string, int](0)
+		for _, k := range []string{"a", "b", "", "\"quoted\"", "ключ"} {
+			m.Put(k, len(k))
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var std map[string]int
+		if err := json.Unmarshal(data, &std); err != nil {
+			t.Fatal(err)
+		}
+		isEqual(t, len(std), 5)
+
+		loaded := New[string, int](0)
+		if err := json.Unmarshal(data, loaded); err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range std {
+			isEqual(t, loaded.Get(k), v)
+		}
+		isEqual(t, loaded.Len(), 5)
+	})
+
+	t.Run("text marshaler keys", func(t *testing.T) {
+		m := NewWithOptions(0, WithSortedJSON[netip.Addr, bool]())
+		m.Put(netip.MustParseAddr("10.0.0.1"), true)
+		m.Put(netip.MustParseAddr("::1"), false)
+
+		data, err := json.Marshal(m)
+		isEqual(t, err, nil)
+		isEqual(t, string(data), `{"10.0.0.1":true,"::1":false}`)
+
+		loaded := New[netip.Addr, bool](0)
+		isEqual(t, json.Unmarshal(data, loaded), nil)
+		isEqual(t, loaded.Get(netip.MustParseAddr("10.0.0.1")), true)
+		isEqual(t, loaded.Len(), 2)
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		type config struct {
+			Limits Hashmap[uint16, float64] `json:"limits"`
+		}
+
+		c := config{Limits: NewWithOptions(0, WithSortedJSON[uint16, float64]())}
+		c.Limits.Put(443, 0.5)
+		c.Limits.Put(80, 1)
+		data, err := json.Marshal(c)
+		isEqual(t, err, nil)
+		isEqual(t, string(data), `{"limits":{"443":0.5,"80":1}}`)
+
+		loaded := config{Limits: New[uint16, float64](0)}
+		isEqual(t, json.Unmarshal(data, &loaded), nil)
+		isEqual(t, loaded.Limits.Get(443), 0.5)
+	})
+
+	t.Run("merge and null", func(t *testing.T) {
+		m := New[string, int](0)
+		m.Put("old", 1)
+		m.Put("a", 1)
+
+		isEqual(t, json.Unmarshal([]byte(`{"a":2,"b":3}`), m), nil)
+		isEqual(t, json.Unmarshal([]byte(`null`), m), nil)
+		isEqual(t, m.Len(), 3)
+		isEqual(t, m.Get("old"), 1)
+		isEqual(t, m.Get("a"), 2)
+		isEqual(t, m.Get("b"), 3)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var unsupported *json.UnsupportedTypeError
+		floats := New[float64, int](0)
+		floats.Put(1.5, 1)
+		if _, err := floats.(json.Marshaler).MarshalJSON(); !errors.As(err, &unsupported) {
+			t.Fatalf("got %v, want an unsupported type error", err)
+		}
+
+		var typeErr *json.UnmarshalTypeError
+		ints := New[int8, int](0)
+		ints.Put(1, 1)
+		for _, data := range []string{`{"300":1}`, `{"x":1}`} {
+			err := json.Unmarshal([]byte(data), ints)
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("got %v for %s, want an unmarshal type error", err, data)
+			}
+		}
+		if err := json.Unmarshal([]byte(`{"1":"not int","2":2}`), ints); err == nil {
+			t.Fatal("expected an error for a string value")
+		}
+		if err := json.Unmarshal([]byte(`[1]`), ints); err == nil {
+			t.Fatal("expected an error for an array")
+		}
+		// the map isn't changed
+		isEqual(t, ints.Len(), 1)
+		isEqual(t, ints.Get(1), 1)
+	})
+}
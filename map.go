@@ -2,9 +2,9 @@ package gomap
 
 import (
 	"fmt"
+	"iter"
 	"strings"
-
-	"github.com/dolthub/maphash"
+	"sync/atomic"
 )
 
 const (
@@ -15,11 +15,18 @@ const (
 
 	ptrSize = 4 << (^uintptr(0) >> 63) // pointer size
 
-	// flags
-	iterator     = 1 // there may be an iterator using buckets
-	oldIterator  = 2 // there may be an iterator using oldbuckets
-	hashWriting  = 4 // a goroutine is writing to the map
-	sameSizeGrow = 8 // the current map growth is to a new map of the same size
+	// flags. iterator and oldIterator are kept in hmap.iterators
+	iterator     = 1  // there may be an iterator using buckets
+	oldIterator  = 2  // there may be an iterator using oldbuckets
+	hashWriting  = 4  // a goroutine is writing to the map
+	sameSizeGrow = 8  // the current map growth is to a new map of the same size
+	shrinking    = 16 // the current map growth is to a new map of the half size
+
+	// The map shrinks when the average load of a bucket drops below
+	// the growth threshold divided by shrinkDivisor (6.5/4 ≈ 1.6).
+	// After shrinking the load is still far enough from the growth threshold,
+	// so a map doesn't shrink and grow back on every Put/Delete.
+	shrinkDivisor = 4
 )
 
 // hmap - map struct
@@ -28,12 +35,25 @@ type hmap[K comparable, V any] struct {
 	B   uint8 // log_2 of # of buckets
 
 	buckets []bucket[K, V]
-	hasher  maphash.Hasher[K] // Go's runtime hasher
+	hasher  Hasher[K]
 
 	oldbuckets   *[]bucket[K, V]
-	numEvacuated uint64 // progress counter for evacuation (buckets less than this have been evacuated)
+	numEvacuated uint64       // progress counter for evacuation (buckets less than this have been evacuated)
+	noverflow    uint32       // number of overflow buckets hanging off the main bucket array
+	guard        *accessGuard // detects concurrent access in the checked mode, nil otherwise
+	hintB        uint8        // B for the size given to New. the map doesn't shrink automatically below it
+
+	keyCodec   Codec[K] // for MarshalBinary and UnmarshalBinary
+	valueCodec Codec[V]
+	sortedJSON bool              // MarshalJSON sorts keys
+	stringLess func(a, b K) bool // String sorts keys by it, nil for the iteration order
+
+	deterministic bool // iterations start at the first bucket
 
 	flags uint8
+	// iterator flags. concurrent iterations are reads, so they are set atomically
+	// and apart from the flags changed by writes
+	iterators atomic.Uint32
 }
 
 type Hashmap[K comparable, V any] interface {
@@ -55,18 +75,75 @@ type Hashmap[K comparable, V any] interface {
 	String() string
 }
 
+// Sequencer - implemented by maps which provide range-over-func iterators.
+//
+//	for k, v := range m.(gomap.Sequencer[string, int]).All() {
+type Sequencer[K comparable, V any] interface {
+	// returns an iterator over key-value pairs from the map.
+	// the iteration order is the same as for Range.
+	All() iter.Seq2[K, V]
+	// returns an iterator over keys in the map
+	Keys() iter.Seq[K]
+	// returns an iterator over values in the map
+	Values() iter.Seq[V]
+}
+
+// Popper - implemented by maps which return the deleted value.
+type Popper[K comparable, V any] interface {
+	// deletes an element from the map and returns its value and true.
+	// returns zero value for <V> and false if there is no value for the given key
+	Pop(key K) (V, bool)
+}
+
+// Shrinker - implemented by maps which can release memory left after deletes.
+type Shrinker interface {
+	// shrinks the map to the smallest size which can hold all its elements
+	Shrink()
+	// rebuilds the map at the same size, dropping empty overflow buckets
+	Compact()
+}
+
+// Clearer - implemented by maps which can be emptied without creating a new one.
+type Clearer interface {
+	// removes all the elements from the map, allocated buckets are kept
+	Clear()
+	// removes all the elements from the map and resizes it for <size> elements
+	Reset(size int)
+}
+
+var (
+	_ Sequencer[string, int] = (*hmap[string, int])(nil)
+	_ Popper[string, int]    = (*hmap[string, int])(nil)
+	_ Shrinker               = (*hmap[string, int])(nil)
+	_ Clearer                = (*hmap[string, int])(nil)
+)
+
 // New - creates a new map for <size> elements
 func New[K comparable, V any](size int) Hashmap[K, V] {
+	return NewWithOptions[K, V](size)
+}
+
+// NewWithOptions - creates a new map for <size> elements configured with the given options
+func NewWithOptions[K comparable, V any](size int, opts ...Option[K, V]) Hashmap[K, V] {
+	return newHmap(size, newOptions(opts))
+}
+
+func newHmap[K comparable, V any](size int, o options[K, V]) *hmap[K, V] {
 	h := new(hmap[K, V])
 
-	B := uint8(0)
-	for overLoadFactor(size, B) {
-		B++
-	}
+	B := fitB(size)
 	h.B = B
+	h.hintB = B
 
 	h.buckets = make([]bucket[K, V], bucketsNum(h.B))
-	h.hasher = maphash.NewHasher[K]()
+	h.hasher = o.hasher
+	h.keyCodec, h.valueCodec = o.keyCodec, o.valueCodec
+	h.sortedJSON = o.sortedJSON
+	h.stringLess = o.stringLess
+	h.deterministic = o.deterministic
+	if o.checked {
+		h.guard = new(accessGuard)
+	}
 
 	return h
 }
@@ -77,16 +154,27 @@ func (h *hmap[K, V]) Get(key K) V {
 }
 
 func (h *hmap[K, V]) Get2(key K) (V, bool) {
+	return h.get2(key, h.hasher.Hash(key))
+}
+
+// get2 - Get2 for the key with the precomputed hash
+func (h *hmap[K, V]) get2(key K, hash uint64) (V, bool) {
+	if h.guard != nil {
+		h.guard.startReading("concurrent map read and map write")
+		defer h.guard.finishReading()
+	}
 	if h.flags&hashWriting != 0 {
 		panic("concurrent map access and write")
 	}
 
-	tophash, targetBucket := h.locateBucket(key)
+	tophash, targetBucket := h.locateHash(hash)
 
 	b := &h.buckets[targetBucket]
 
 	if h.isGrowing() {
-		oldB := &(*h.oldbuckets)[targetBucket&h.oldBucketMask()]
+		// the hash is used instead of targetBucket
+		// because old buckets are twice as many as new ones during shrinking
+		oldB := &(*h.oldbuckets)[hash&h.oldBucketMask()]
 		if !oldB.isEvacuated() {
 			b = oldB
 		}
@@ -95,64 +183,150 @@ func (h *hmap[K, V]) Get2(key K) (V, bool) {
 	return b.Get(key, tophash)
 }
 
+// lookup - finds the cell of the key in the current buckets,
+// or in the old bucket if it hasn't been evacuated yet.
+// returns the first bucket of the chain as well, the cell can be deleted with head.deleteAt
+func (h *hmap[K, V]) lookup(key K, hash uint64) (head, bkt *bucket[K, V], i int, found bool) {
+	tophash, targetBucket := h.locateHash(hash)
+
+	b := &h.buckets[targetBucket]
+	if h.inOldBucket(hash) {
+		b = &(*h.oldbuckets)[hash&h.oldBucketMask()]
+	}
+
+	bkt, i, found = b.find(key, tophash)
+	return b, bkt, i, found
+}
+
+// inOldBucket - reports whether keys with the hash are in an old bucket which isn't evacuated yet
+func (h *hmap[K, V]) inOldBucket(hash uint64) bool {
+	return h.isGrowing() && !(*h.oldbuckets)[hash&h.oldBucketMask()].isEvacuated()
+}
+
 func (h *hmap[K, V]) Put(key K, value V) {
-	if h.flags&hashWriting != 0 {
-		panic("concurrent map access and write")
+	h.put(key, h.hasher.Hash(key), value)
+}
+
+// put - Put for the key with the precomputed hash
+func (h *hmap[K, V]) put(key K, hash uint64, value V) {
+	h.startWriting()
+
+	tophash, targetBucket := h.prepareWrite(hash)
+
+	isAdded, isOverflowed := h.buckets[targetBucket].Put(key, tophash, value)
+	if isAdded {
+		h.len++
 	}
-	h.flags ^= hashWriting
+	if isOverflowed {
+		h.noverflow++
+	}
+	h.finishWriting()
+}
+
+func (h *hmap[K, V]) Delete(key K) {
+	h.Pop(key)
+}
+
+func (h *hmap[K, V]) Pop(key K) (V, bool) {
+	return h.pop(key, h.hasher.Hash(key))
+}
+
+// pop - Pop for the key with the precomputed hash
+func (h *hmap[K, V]) pop(key K, hash uint64) (V, bool) {
+	h.startWriting()
+	value, deleted := h.remove(key, hash)
+	h.finishWriting()
+
+	return value, deleted
+}
 
-	tophash, targetBucket := h.locateBucket(key)
+// remove - deletes the key, the map must be marked as being written
+func (h *hmap[K, V]) remove(key K, hash uint64) (V, bool) {
+	tophash, targetBucket := h.locateHash(hash)
+
+	// evacuate old bucket first, so deletes make progress on shrinking
+	if h.isGrowing() {
+		h.growWork(targetBucket)
+	}
+
+	value, deleted := h.buckets[targetBucket].Delete(key, tophash)
+	if deleted {
+		h.deleted()
+	}
 
+	return value, deleted
+}
+
+// prepareWrite - starts growth if needed, locates the bucket for the key
+// and evacuates the corresponding old bucket, so the key can be put into the new one.
+func (h *hmap[K, V]) prepareWrite(hash uint64) (tophash uint8, targetBucket uint64) {
 	// start growing if adding an element will trigger overload
-	if !h.isGrowing() && overLoadFactor(h.len+1, h.B) {
+	// or if there are too many overflow buckets
+	if !h.isGrowing() && (overLoadFactor(h.len+1, h.B) || tooManyOverflowBuckets(h.noverflow, h.B)) {
 		h.startGrowth()
 	}
 
+	// the bucket must be located after the growth has been started
+	// because B could be changed
+	tophash, targetBucket = h.locateHash(hash)
+
 	// evacuate old bucket first
 	if h.isGrowing() {
 		h.growWork(targetBucket)
 	}
 
-	if h.buckets[targetBucket].Put(key, tophash, value) {
-		h.len++
-	}
-	if h.flags&hashWriting == 0 {
-		panic("concurrent map writes")
+	return tophash, targetBucket
+}
+
+// deleted - updates the map state after an element has been deleted
+func (h *hmap[K, V]) deleted() {
+	h.len--
+
+	// start shrinking if the map became too sparse,
+	// but not below the size it has been created for
+	if !h.isGrowing() && h.B > h.hintB && underLoadFactor(h.len, h.B) {
+		h.startShrink()
 	}
-	h.flags &^= hashWriting
 }
 
-func (h *hmap[K, V]) Delete(key K) {
+// startWriting - marks the map as being written.
+// panics if another goroutine is writing to the map (or reading it in the checked mode)
+func (h *hmap[K, V]) startWriting() {
+	if h.guard != nil {
+		h.guard.startWriting()
+	}
 	if h.flags&hashWriting != 0 {
 		panic("concurrent map writes")
 	}
-
 	h.flags ^= hashWriting
+}
 
-	tophash, targetBucket := h.locateBucket(key)
-
-	b := &h.buckets[targetBucket]
-
-	if h.isGrowing() {
-		oldB := &(*h.oldbuckets)[targetBucket&h.oldBucketMask()]
-		if !oldB.isEvacuated() {
-			b = oldB
-		}
-	}
-
-	if deleted := b.Delete(key, tophash); deleted {
-		h.len--
-	}
+func (h *hmap[K, V]) finishWriting() {
 	if h.flags&hashWriting == 0 {
 		panic("concurrent map writes")
 	}
 	h.flags &^= hashWriting
+	if validateWrites {
+		if err := h.validate(); err != nil {
+			panic("gomap: broken map invariant: " + err.Error())
+		}
+	}
+	if h.guard != nil {
+		h.guard.finishWriting()
+	}
 }
 
-// locateBucket - returns bucket index, where to put/search a value
-// and tophash value from hash of the given key
-func (h *hmap[K, V]) locateBucket(key K) (tophash uint8, targetBucket uint64) {
-	hash := h.hasher.Hash(key)
+// locateBucket - returns bucket index, where to put/search a value,
+// hash of the given key and tophash value from it
+func (h *hmap[K, V]) locateBucket(key K) (hash uint64, tophash uint8, targetBucket uint64) {
+	hash = h.hasher.Hash(key)
+	tophash, targetBucket = h.locateHash(hash)
+
+	return hash, tophash, targetBucket
+}
+
+// locateHash - returns tophash and bucket index for the precomputed hash of a key
+func (h *hmap[K, V]) locateHash(hash uint64) (tophash uint8, targetBucket uint64) {
 	tophash = topHash(hash)
 	mask := bucketMask(h.B)
 
@@ -168,12 +342,23 @@ func (h *hmap[K, V]) locateBucket(key K) (tophash uint8, targetBucket uint64) {
 }
 
 func (h *hmap[K, V]) String() string {
+	return formatMap(h.Range, h.stringLess)
+}
+
+// formatMap - formats the elements produced by <rangeFunc> as go-map[k:v k:v].
+// if <less> isn't nil, the elements are sorted by their keys
+func formatMap[K comparable, V any](rangeFunc func(f func(k K, v V) bool), less func(a, b K) bool) string {
 	buf := strings.Builder{}
 	buf.WriteString("go-map[")
-	h.Range(func(k K, v V) bool {
+	add := func(k K, v V) bool {
 		buf.WriteString(fmt.Sprintf("%v:%v ", k, v))
 		return true
-	})
+	}
+	if less != nil {
+		rangeSorted(rangeFunc, less, add)
+	} else {
+		rangeFunc(add)
+	}
 
 	return strings.TrimRight(buf.String(), " ") + "]"
 }
@@ -198,11 +383,39 @@ func bucketMask(b uint8) uint64 {
 	return bucketsNum(b) - 1
 }
 
+// fitB returns the smallest B which holds <size> elements without exceeding the load factor
+func fitB(size int) uint8 {
+	B := uint8(0)
+	for overLoadFactor(size, B) {
+		B++
+	}
+	return B
+}
+
 // overLoadFactor reports whether count items placed in 1<<B buckets is over loadFactor.
 func overLoadFactor(size int, B uint8) bool {
 	return size > bucketSize && uint64(size) > loadFactorNum*(bucketsNum(B)/loadFactorDen)
 }
 
+// underLoadFactor reports whether count items placed in 1<<B buckets is so few
+// that the map can be shrunk to the half size.
+func underLoadFactor(count int, B uint8) bool {
+	return B > 0 && uint64(count) < loadFactorNum*(bucketsNum(B)/loadFactorDen)/shrinkDivisor
+}
+
+// tooManyOverflowBuckets reports whether noverflow buckets is too many for a map with 1<<B buckets.
+// Note that most of these overflow buckets must be in sparse use;
+// if use was dense, then we'd have already triggered regular map growth.
+func tooManyOverflowBuckets(noverflow uint32, B uint8) bool {
+	// If the threshold is too low, we do extraneous work.
+	// If the threshold is too high, maps that grow and shrink can hold on to lots of unused memory.
+	// "too many" means (approximately) as many overflow buckets as regular buckets.
+	if B > 15 {
+		B = 15
+	}
+	return noverflow >= uint32(1)<<B
+}
+
 func (m *hmap[K, V]) Range(f func(k K, v V) bool) {
 	iter := iterInit(m)
 	for iter.key != nil && iter.elem != nil {
@@ -213,15 +426,128 @@ func (m *hmap[K, V]) Range(f func(k K, v V) bool) {
 	}
 }
 
+func (m *hmap[K, V]) RangeSorted(less func(a, b K) bool, f func(k K, v V) bool) {
+	rangeSorted(m.Range, less, f)
+}
+
+func (m *hmap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.Range(yield)
+	}
+}
+
+func (m *hmap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+func (m *hmap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
 func (m *hmap[K, V]) Len() int {
 	return m.len
 }
 
+// Shrink - shrinks the map to the smallest number of buckets which holds
+// all the elements without exceeding the load factor.
+// Unlike the automatic shrinking the evacuation is done at once.
+func (m *hmap[K, V]) Shrink() {
+	m.startWriting()
+
+	m.finishGrowth()
+	for m.B > 0 && !overLoadFactor(m.len, m.B-1) {
+		m.startShrink()
+		m.finishGrowth()
+	}
+
+	m.finishWriting()
+}
+
+// Clear - removes all the elements. Unlike New it keeps the current bucket array,
+// overflow buckets are dropped and the growth in progress is cancelled.
+func (m *hmap[K, V]) Clear() {
+	m.startWriting()
+
+	m.clear()
+	// clear() zeroes tophashes(emptyRest), keys, values and pointers to overflow buckets
+	clear(m.buckets)
+
+	m.finishWriting()
+}
+
+// Reset - removes all the elements and resizes the map for <size> elements.
+func (m *hmap[K, V]) Reset(size int) {
+	m.startWriting()
+
+	m.clear()
+	m.B = fitB(size)
+	m.hintB = m.B
+	m.buckets = make([]bucket[K, V], bucketsNum(m.B))
+
+	m.finishWriting()
+}
+
+// clear resets the map state except the main bucket array.
+func (m *hmap[K, V]) clear() {
+	// mark all the cells as empty, so iterators which still use
+	// these buckets stop returning the values
+	markEmpty(m.buckets)
+	if m.isGrowing() {
+		markEmpty(*m.oldbuckets)
+	}
+
+	m.len = 0
+	m.oldbuckets = nil
+	m.numEvacuated = 0
+	m.noverflow = 0
+	m.flags &^= sameSizeGrow | shrinking
+}
+
+func markEmpty[K comparable, V any](buckets []bucket[K, V]) {
+	for i := range buckets {
+		for b := &buckets[i]; b != nil; b = b.overflow {
+			b.tophash = [bucketSize]uint8{} // emptyRest
+		}
+	}
+}
+
+// Compact - moves all the elements into new buckets of the same size.
+// Chains of overflow buckets left after deletes are dropped.
+func (m *hmap[K, V]) Compact() {
+	m.startWriting()
+
+	m.finishGrowth()
+	if m.noverflow > 0 {
+		m.moveToNewBuckets(m.B, sameSizeGrow)
+		m.finishGrowth()
+	}
+
+	m.finishWriting()
+}
+
 // sameSizeGrow reports whether the current growth is to a map of the same size.
 func (h *hmap[K, V]) sameSizeGrow() bool {
 	return h.flags&sameSizeGrow != 0
 }
 
+// isShrinking reports whether the current growth is to a map of the half size.
+func (h *hmap[K, V]) isShrinking() bool {
+	return h.flags&shrinking != 0
+}
+
+// growsBigger reports whether the current growth is to a map of the double size.
+func (h *hmap[K, V]) growsBigger() bool {
+	return h.flags&(sameSizeGrow|shrinking) == 0
+}
+
 func (m *hmap[K, V]) isGrowing() bool {
 	return m.oldbuckets != nil
 }
@@ -238,65 +564,85 @@ func (m *hmap[K, V]) growWork(bucket uint64) {
 }
 
 func (m *hmap[K, V]) evacuate(oldbucket uint64) {
-	b := &(*m.oldbuckets)[oldbucket]
 	newBit := m.numOldBuckets()
 
-	if !b.isEvacuated() {
+	if m.isShrinking() {
+		// two old buckets are merged into one new bucket.
+		// they are evacuated together, so a new bucket is either empty
+		// or has all the values from both of them
+		newbucket := oldbucket & bucketMask(m.B)
+		halfs := [2]evacDst[K, V]{{b: &m.buckets[newbucket]}}
+		m.evacuateBucket(&(*m.oldbuckets)[newbucket], &halfs, newBit)
+		m.evacuateBucket(&(*m.oldbuckets)[newbucket+bucketsNum(m.B)], &halfs, newBit)
+	} else {
 		// two halfs of the new buckets
 		halfs := [2]evacDst[K, V]{{b: &m.buckets[oldbucket]}}
 
-		if !m.sameSizeGrow() {
+		if m.growsBigger() {
 			// Only calculate y pointers if we're growing bigger.
 			// Otherwise GC can see bad pointers.
 			halfs[1].b = &m.buckets[oldbucket+newBit]
 		}
+		m.evacuateBucket(&(*m.oldbuckets)[oldbucket], &halfs, newBit)
+	}
 
-		for ; b != nil; b = b.overflow {
-			// moving all values from the old bucket to the new one
-			for i := 0; i < bucketSize; i++ {
-				top := b.tophash[i]
-
-				if isCellEmpty(top) {
-					b.tophash[i] = evacuatedEmpty
-					continue
-				}
+	if oldbucket == m.numEvacuated {
+		m.advanceEvacuationMark(newBit)
+	}
+}
 
-				key := &b.keys[i]
-				value := &b.values[i]
+// evacuateBucket moves all values from the old bucket to the given destinations
+func (m *hmap[K, V]) evacuateBucket(b *bucket[K, V], halfs *[2]evacDst[K, V], newBit uint64) {
+	if b.isEvacuated() {
+		return
+	}
 
-				hash := m.hasher.Hash(*key)
+	for ; b != nil; b = b.overflow {
+		// moving all values from the old bucket to the new one
+		for i := 0; i < bucketSize; i++ {
+			top := b.tophash[i]
 
-				// decide where to evacuate the element.
-				// the first or the second half of the new buckets
-				//
-				// newBit == # of prev buckets. it's called like that because of it's purpose
-				// the value represents new bit of our new mask(# of curr buckets - 1)
-				// if newBit == 8 (1000) then newMask == 15(1111) and oldMask == 7(0111)
-				// and in that case only the 4th bit(from the end) of mask matters
-				// because it decides whether targetBucket changes or not.
+			if isCellEmpty(top) {
+				b.tophash[i] = evacuatedEmpty
+				continue
+			}
 
-				var useSecond uint8
-				if !m.sameSizeGrow() && hash&newBit != 0 {
+			key := &b.keys[i]
+			value := &b.values[i]
+
+			// decide where to evacuate the element.
+			// the first or the second half of the new buckets
+			//
+			// newBit == # of prev buckets. it's called like that because of it's purpose
+			// the value represents new bit of our new mask(# of curr buckets - 1)
+			// if newBit == 8 (1000) then newMask == 15(1111) and oldMask == 7(0111)
+			// and in that case only the 4th bit(from the end) of mask matters
+			// because it decides whether targetBucket changes or not.
+
+			var useSecond uint8
+			if m.growsBigger() {
+				if *key != *key {
+					// runtime/map.go:1210
+					// NaNs can't be looked up, the iterator expects them
+					// in the half chosen by the low bit of tophash
+					useSecond = top & 1
+				} else if hash := m.hasher.Hash(*key); hash&newBit != 0 {
 					useSecond = 1
 				}
+			}
 
-				// evacuatedFirst + useSecond == evaluatedSecond
-				b.tophash[i] = evacuatedFirst + useSecond
-				dst := &halfs[useSecond]
-				// check bounds
-				if dst.i == bucketSize {
-					dst.b = newOverflow(dst.b)
-					dst.i = 0
-				}
-				dst.b.putAt(*key, top, *value, dst.i)
-				dst.i++
+			// evacuatedFirst + useSecond == evaluatedSecond
+			b.tophash[i] = evacuatedFirst + useSecond
+			dst := &halfs[useSecond]
+			// check bounds
+			if dst.i == bucketSize {
+				dst.b = m.newOverflow(dst.b)
+				dst.i = 0
 			}
+			dst.b.putAt(*key, top, *value, dst.i)
+			dst.i++
 		}
 	}
-
-	if oldbucket == m.numEvacuated {
-		m.advanceEvacuationMark(newBit)
-	}
 }
 
 func (m *hmap[K, V]) advanceEvacuationMark(newBit uint64) {
@@ -314,7 +660,14 @@ func (m *hmap[K, V]) advanceEvacuationMark(newBit uint64) {
 	if m.numEvacuated == newBit { // newbit == # of oldbuckets
 		// Growing is all done. Free old main bucket array.
 		m.oldbuckets = nil
-		m.flags &^= sameSizeGrow
+		m.flags &^= sameSizeGrow | shrinking
+	}
+}
+
+// finishGrowth evacuates all the remaining old buckets
+func (m *hmap[K, V]) finishGrowth() {
+	for m.isGrowing() {
+		m.evacuate(m.numEvacuated)
 	}
 }
 
@@ -327,9 +680,12 @@ type evacDst[K comparable, V any] struct {
 // noldbuckets calculates the number of buckets prior to the current map growth.
 func (m *hmap[K, V]) numOldBuckets() uint64 {
 	oldB := m.B
-	if !m.sameSizeGrow() {
+	if m.growsBigger() {
 		oldB--
 	}
+	if m.isShrinking() {
+		oldB++
+	}
 
 	return bucketsNum(oldB)
 }
@@ -340,46 +696,51 @@ func (m *hmap[K, V]) oldBucketMask() uint64 {
 }
 
 func (m *hmap[K, V]) startGrowth() {
+	// If we've hit the load factor, get bigger.
+	// Otherwise, there are too many overflow buckets,
+	// so keep the same number of buckets and "grow" laterally.
+	bigger := uint8(1)
+	if !overLoadFactor(m.len+1, m.B) {
+		bigger = 0
+	}
+
+	if bigger == 0 {
+		m.moveToNewBuckets(m.B, sameSizeGrow)
+		return
+	}
+	m.moveToNewBuckets(m.B+1, 0)
+}
+
+func (m *hmap[K, V]) startShrink() {
+	m.moveToNewBuckets(m.B-1, shrinking)
+}
+
+// moveToNewBuckets allocates 1<<B new buckets and makes the current ones old.
+// growthFlag describes the kind of the growth: sameSizeGrow, shrinking or 0 for growing bigger.
+func (m *hmap[K, V]) moveToNewBuckets(B uint8, growthFlag uint8) {
 	oldBuckets := m.buckets
-	m.B++
+	m.B = B
 	m.buckets = make([]bucket[K, V], bucketsNum(m.B))
 	m.oldbuckets = &oldBuckets
 	m.numEvacuated = 0
+	m.noverflow = 0
 
-	flags := m.flags &^ (iterator | oldIterator) // remove iterators flags
-	if m.flags&iterator != 0 {
-		flags |= oldIterator
+	// iterators of the current buckets become iterators of the old ones
+	if m.iterators.Load()&iterator != 0 {
+		m.iterators.Store(oldIterator)
+	} else {
+		m.iterators.Store(0)
 	}
+	m.flags |= growthFlag
 
 	// actual growth happens in the evacuate() and growWork() functions
 }
 
-func newOverflow[K comparable, V any](b *bucket[K, V]) *bucket[K, V] {
+func (m *hmap[K, V]) newOverflow(b *bucket[K, V]) *bucket[K, V] {
 	if b.overflow == nil {
 		b.overflow = &bucket[K, V]{}
+		m.noverflow++
 	}
 
 	return b.overflow
 }
-
-func (m *hmap[K, V]) debug() {
-	fmt.Println("main buckets:")
-	for i, b := range m.buckets {
-		bk := &b
-		for bk != nil {
-			fmt.Printf("\t\t%d - %s\n", i, bk.debug())
-			bk = bk.overflow
-		}
-	}
-
-	if m.oldbuckets != nil {
-		fmt.Println("old buckets:")
-		for i, b := range *m.oldbuckets {
-			bk := &b
-			for bk != nil {
-				fmt.Printf("\t\t%d - %s\n", i, bk.debug())
-				bk = bk.overflow
-			}
-		}
-	}
-}
@@ -13,12 +13,16 @@ func BenchmarkGet(b *testing.B) {
 	for _, n := range sizes {
 		keys := make([]string, 0, n)
 		mm := New[string, int64](n)
+		swiss := NewSwiss[string, int64](n)
+		robinHood := NewRobinHood[string, int64](n)
 		openAddrMap := hashmap.New[string, int64](n)
 		stdm := make(map[string]int64, n)
 
 		for i := 0; i < n; i++ {
 			k := fmt.Sprintf("key__%d", i)
 			mm.Put(k, int64(i)*2)
+			swiss.Put(k, int64(i)*2)
+			robinHood.Put(k, int64(i)*2)
 			stdm[k] = int64(i) * 2
 			openAddrMap.Set(k, int64(i)*2)
 			keys = append(keys, k)
@@ -37,6 +41,32 @@ func BenchmarkGet(b *testing.B) {
 			_ = got
 		})
 
+		j = 0
+		b.Run(fmt.Sprintf("swiss-map   %d", n), func(b *testing.B) {
+			var got int64
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+				}
+				got = swiss.Get(keys[j])
+				j++
+			}
+			_ = got
+		})
+
+		j = 0
+		b.Run(fmt.Sprintf("robin-hood  %d", n), func(b *testing.B) {
+			var got int64
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+				}
+				got = robinHood.Get(keys[j])
+				j++
+			}
+			_ = got
+		})
+
 		j = 0
 		b.Run(fmt.Sprintf("STD-map     %d", n), func(b *testing.B) {
 			var got int64
@@ -86,6 +116,34 @@ func BenchmarkPut(b *testing.B) {
 			}
 		})
 
+		j = 0
+		multiplier = 1
+		swiss := NewSwiss[string, int64](n)
+		b.Run(fmt.Sprintf("swiss-map   %d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+					multiplier += 1
+				}
+				swiss.Put(keys[j], int64(j*multiplier))
+				j++
+			}
+		})
+
+		j = 0
+		multiplier = 1
+		robinHood := NewRobinHood[string, int64](n)
+		b.Run(fmt.Sprintf("robin-hood  %d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+					multiplier += 1
+				}
+				robinHood.Put(keys[j], int64(j*multiplier))
+				j++
+			}
+		})
+
 		j = 0
 		multiplier = 1
 		stdm := make(map[string]int64, n)
@@ -99,6 +157,20 @@ func BenchmarkPut(b *testing.B) {
 				j++
 			}
 		})
+
+		j = 0
+		multiplier = 1
+		openAddrMap := hashmap.New[string, int64](n)
+		b.Run(fmt.Sprintf("tidwall-hashmap (open-addressing hashmap) %d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+					multiplier += 1
+				}
+				openAddrMap.Set(keys[j], int64(j*multiplier))
+				j++
+			}
+		})
 	}
 }
 
@@ -132,6 +204,38 @@ func BenchmarkPutWithOverflow(b *testing.B) {
 			}
 		})
 
+		swiss := NewSwiss[string, someStruct](startSize)
+		j = 0
+		multiplier = 1
+		b.Run(fmt.Sprintf("swiss    (string key)%d", n), func(b *testing.B) {
+			var key string
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+					multiplier += 1
+				}
+				key = keys[j]
+				swiss.Put(key, someStruct{x: key, y: j * multiplier})
+				j++
+			}
+		})
+
+		robinHood := NewRobinHood[string, someStruct](startSize)
+		j = 0
+		multiplier = 1
+		b.Run(fmt.Sprintf("robin    (string key)%d", n), func(b *testing.B) {
+			var key string
+			for i := 0; i < b.N; i++ {
+				if j == n {
+					j = 0
+					multiplier += 1
+				}
+				key = keys[j]
+				robinHood.Put(key, someStruct{x: key, y: j * multiplier})
+				j++
+			}
+		})
+
 		stdm := make(map[string]someStruct, startSize)
 		j = 0
 		multiplier = 1
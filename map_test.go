@@ -2,8 +2,10 @@ package gomap
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -58,6 +60,7 @@ func TestMap(t *testing.T) {
 }
 
 func isEqual(t *testing.T, got interface{}, want interface{}) {
+	t.Helper()
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("result is not equal\ngot:  %+v\nwant: %+v\n", got, want)
 	}
@@ -74,8 +77,10 @@ func TestBucketOverflow(t *testing.T) {
 		mm.Put(fmt.Sprintf("%s%d", prefix, v), v)
 	}
 
-	dm := mm.(*hmap[string, int])
-	dm.debug()
+	dump := strings.Builder{}
+	err := mm.(Dumper).Dump(&dump, DumpOptions{})
+	isEqual(t, err, nil)
+	t.Log(dump.String())
 
 	for _, v := range values {
 		got := mm.Get(fmt.Sprintf("%s%d", prefix, v))
@@ -155,22 +160,144 @@ func TestRange(t *testing.T) {
 	isEqual(t, wantValues, gotValues)
 }
 
+// operations decoded from the fuzzer input
+const (
+	opPut = iota
+	opDelete
+	opGet
+	opPop
+	opPutMany
+	opRange
+	opPartialRange
+	opResize
+	numOps
+)
+
+// FuzzOperations - decodes the input into a sequence of operations over a small key space,
+// applies them to the map and to a built-in map and checks the results and the map invariants after every step.
+// the first byte chooses the initial size and the hasher, the following pairs of bytes are an operation and its argument.
+func FuzzOperations(f *testing.F) {
+	f.Add([]byte{0, opPutMany, 200, opRange, 0, opDelete, 3, opGet, 3})
+	f.Add([]byte{1, opPutMany, 31, opPutMany, 63, opPartialRange, 5, opPutMany, 95, opPartialRange, 15})
+	f.Add([]byte{2, opPutMany, 255, opPutMany, 127, opPartialRange, 3, opResize, 0, opRange, 0})
+	f.Add([]byte{1, opPutMany, 255, opPutMany, 223, opResize, 1, opPartialRange, 7, opPop, 200})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+
+		// keys are spread over all the buckets, or put into the first 4 buckets
+		// to make overflow chains and same size growth
+		hasher := HasherFunc[uint8](func(k uint8) uint64 { return uint64(k) * 0x9E3779B97F4A7C15 })
+		if data[0]&1 != 0 {
+			hasher = func(k uint8) uint64 { return uint64(k%4) | uint64(k)<<56 }
+		}
+		m := newHmap(int(data[0]>>1&7)*8, newOptions([]Option[uint8, int]{WithHasher[uint8, int](hasher)}))
+		std := make(map[uint8]int)
+
+		for step, i := 0, 1; i+1 < len(data); step, i = step+1, i+2 {
+			op, arg := data[i]%numOps, data[i+1]
+
+			switch op {
+			case opPut:
+				m.Put(arg, step)
+				std[arg] = step
+			case opDelete:
+				m.Delete(arg)
+				delete(std, arg)
+			case opGet:
+				v, ok := m.Get2(arg)
+				want, wantOk := std[arg]
+				isEqual(t, ok, wantOk)
+				isEqual(t, v, want)
+			case opPop:
+				v, ok := m.Pop(arg)
+				want, wantOk := std[arg]
+				isEqual(t, ok, wantOk)
+				isEqual(t, v, want)
+				delete(std, arg)
+			case opPutMany:
+				for k := arg; k > arg-arg%32; k-- {
+					m.Put(k, step)
+					std[k] = step
+				}
+			case opRange:
+				isEqual(t, maps.Collect(m.All()), std)
+			case opPartialRange:
+				// stop after <arg%16> elements, write to the map on every step
+				limit := int(arg%16) + 1
+				seen := make(map[uint8]bool)
+				deleted := make(map[uint8]bool)
+				m.Range(func(k uint8, v int) bool {
+					if seen[k] && !deleted[k] {
+						t.Fatalf("key %d has been produced twice", k)
+					}
+					seen[k] = true
+					want, ok := std[k]
+					if !ok {
+						t.Fatalf("missing key %d has been produced", k)
+					}
+					isEqual(t, v, want)
+
+					m.Put(k+arg, step)
+					std[k+arg] = step
+					m.Delete(k ^ arg)
+					delete(std, k^arg)
+					deleted[k^arg] = true
+					return len(seen) < limit
+				})
+			case opResize:
+				switch arg % 3 {
+				case 0:
+					m.Shrink()
+				case 1:
+					m.Compact()
+				case 2:
+					m.Clear()
+					clear(std)
+				}
+			}
+
+			isEqual(t, m.Len(), len(std))
+			if err := m.validate(); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		}
+		isEqual(t, maps.Collect(m.All()), std)
+	})
+}
+
 type testcase[K comparable, V any] struct{}
 
 func (tt testcase[K, V]) test(t *testing.T, keys []K, values []V) {
 	if len(keys) != len(values) {
 		t.Fatalf("lengths of keys(%d) and values(%d) must be equal", len(keys), len(values))
 	}
-	m := New[K, V](len(keys))
 
-	for i, k := range keys {
-		m.Put(k, values[i])
+	impls := []struct {
+		name   string
+		newMap func(size int) Hashmap[K, V]
+	}{
+		{"hmap", New[K, V]},
+		{"swiss", func(size int) Hashmap[K, V] { return NewSwiss[K, V](size) }},
+		{"robin hood", func(size int) Hashmap[K, V] { return NewRobinHood[K, V](size) }},
+	}
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			m := impl.newMap(len(keys))
 
-		got, ok := m.Get2(k)
-		isEqual(t, ok, true)
-		isEqual(t, got, values[i])
+			for i, k := range keys {
+				m.Put(k, values[i])
+
+				got, ok := m.Get2(k)
+				isEqual(t, ok, true)
+				isEqual(t, got, values[i])
+			}
+		})
 	}
 }
+
 func TestDifferentKeyTypes(t *testing.T) {
 	t.Run("struct", func(t *testing.T) {
 		type keyStruct struct {
@@ -240,3 +367,565 @@ func TestDifferentKeyTypes(t *testing.T) {
 		tests.test(t, []chan int{ch1, ch2, ch3, ch4}, []int{1, 2, 3, 4})
 	})
 }
+
+// keysForBucket returns n keys which are placed into the given bucket
+func keysForBucket(m *hmap[int, int], bucket uint64, n int, from int) []int {
+	keys := make([]int, 0, n)
+	for k := from; len(keys) < n; k++ {
+		if _, _, b := m.locateBucket(k); b == bucket {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// newWithOverflowBuckets returns a map which will start the same size growth on the next Put
+func newWithOverflowBuckets(t *testing.T) (*hmap[int, int], []int) {
+	m := New[int, int](20).(*hmap[int, int])
+	isEqual(t, m.B, uint8(2))
+
+	// two overflow buckets for the first bucket
+	for _, k := range keysForBucket(m, 0, 3*bucketSize, 0) {
+		m.Put(k, k)
+	}
+	for _, k := range keysForBucket(m, 0, 3*bucketSize, 0) {
+		m.Delete(k)
+	}
+
+	// and two more for the second one
+	keys := keysForBucket(m, 1, 2*bucketSize+1, 0)
+	for _, k := range keys {
+		m.Put(k, k)
+	}
+	isEqual(t, m.noverflow, uint32(4))
+	isEqual(t, m.isGrowing(), false)
+	mustValidate(t, m)
+
+	return m, keys
+}
+
+func TestSameSizeGrow(t *testing.T) {
+	m, keys := newWithOverflowBuckets(t)
+
+	extra := keysForBucket(m, 2, 1, 0)[0]
+	m.Put(extra, extra)
+	keys = append(keys, extra)
+	mustValidate(t, m)
+
+	isEqual(t, m.isGrowing(), true)
+	isEqual(t, m.sameSizeGrow(), true)
+	isEqual(t, m.B, uint8(2))
+
+	// values are accessible during evacuation
+	for _, k := range keys {
+		isEqual(t, m.Get(k), k)
+	}
+
+	// finish evacuation
+	for m.isGrowing() {
+		m.Put(-1, -1)
+		mustValidate(t, m)
+		m.Delete(-1)
+		mustValidate(t, m)
+	}
+
+	isEqual(t, m.sameSizeGrow(), false)
+	isEqual(t, m.B, uint8(2))
+	isEqual(t, m.Len(), len(keys))
+	// deleted cells of the first bucket have been dropped
+	isEqual(t, m.buckets[0].overflow == nil, true)
+	isEqual(t, m.noverflow, uint32(2))
+	isEqual(t, m.buckets[1].overflow.overflow != nil, true)
+
+	for _, k := range keys {
+		isEqual(t, m.Get(k), k)
+	}
+}
+
+func TestRangeDuringSameSizeGrow(t *testing.T) {
+	t.Run("growth started during iteration", func(t *testing.T) {
+		m, keys := newWithOverflowBuckets(t)
+		extra := keysForBucket(m, 2, bucketSize, 0)
+
+		seen := make(map[int]int, len(keys))
+		m.Range(func(k, v int) bool {
+			isEqual(t, v, k)
+			seen[k]++
+			for _, e := range extra {
+				m.Put(e, e)
+				mustValidate(t, m)
+			}
+			return true
+		})
+
+		isEqual(t, m.sameSizeGrow() || !m.isGrowing(), true)
+		for _, k := range keys {
+			isEqual(t, seen[k], 1)
+		}
+		for k, n := range seen {
+			if n != 1 {
+				t.Fatalf("key %d has been seen %d times", k, n)
+			}
+		}
+	})
+
+	t.Run("iteration started during growth", func(t *testing.T) {
+		m, keys := newWithOverflowBuckets(t)
+		extra := keysForBucket(m, 2, 1, 0)[0]
+		m.Put(extra, extra)
+		keys = append(keys, extra)
+		isEqual(t, m.sameSizeGrow(), true)
+
+		seen := make(map[int]int, len(keys))
+		m.Range(func(k, v int) bool {
+			isEqual(t, v, k)
+			seen[k]++
+			return true
+		})
+
+		isEqual(t, len(seen), len(keys))
+		for _, k := range keys {
+			isEqual(t, seen[k], 1)
+		}
+	})
+}
+
+func TestRangeDuringGrowth(t *testing.T) {
+	m := New[int, int](0)
+	n := 50
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	seen := make(map[int]int, n)
+	next := n
+	m.Range(func(k, v int) bool {
+		seen[k]++
+		// trigger growth several times
+		for i := 0; i < 20; i++ {
+			m.Put(next, next)
+			next++
+		}
+		return true
+	})
+
+	for i := 0; i < n; i++ {
+		isEqual(t, seen[i], 1)
+	}
+	for k, n := range seen {
+		if n != 1 {
+			t.Fatalf("key %d has been seen %d times", k, n)
+		}
+	}
+	for i := 0; i < next; i++ {
+		isEqual(t, m.Get(i), i)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	t.Run("automatic", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		n := 1000
+		for i := 0; i < n; i++ {
+			m.Put(i, i)
+		}
+		maxB := m.B
+
+		for i := 0; i < n-10; i++ {
+			m.Delete(i)
+			isEqual(t, m.Len(), n-i-1)
+			mustValidate(t, m)
+		}
+		m.finishGrowth()
+
+		if m.B >= maxB {
+			t.Fatalf("map hasn't been shrunk: B=%d", m.B)
+		}
+		for i := n - 10; i < n; i++ {
+			isEqual(t, m.Get(i), i)
+		}
+	})
+
+	t.Run("not below the initial size", func(t *testing.T) {
+		m := New[int, int](1000).(*hmap[int, int])
+		B := m.B
+		for i := 0; i < 1000; i++ {
+			m.Put(i, i)
+		}
+		for i := 0; i < 1000; i++ {
+			m.Delete(i)
+		}
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.B, B)
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		m := New[int, int](1000).(*hmap[int, int])
+		for i := 0; i < 1000; i++ {
+			m.Put(i, i)
+		}
+		for i := 10; i < 1000; i++ {
+			m.Delete(i)
+		}
+
+		m.Shrink()
+		mustValidate(t, m)
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.B, New[int, int](10).(*hmap[int, int]).B)
+		isEqual(t, m.Len(), 10)
+		for i := 0; i < 10; i++ {
+			isEqual(t, m.Get(i), i)
+		}
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		m, keys := newWithOverflowBuckets(t)
+
+		m.Compact()
+		mustValidate(t, m)
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.B, uint8(2))
+		isEqual(t, m.buckets[0].overflow == nil, true)
+		for _, k := range keys {
+			isEqual(t, m.Get(k), k)
+		}
+	})
+}
+
+func TestRangeDuringShrink(t *testing.T) {
+	n := 1000
+
+	t.Run("delete during iteration", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < n; i++ {
+			m.Put(i, i)
+		}
+
+		seen := make(map[int]int, n)
+		m.Range(func(k, v int) bool {
+			isEqual(t, v, k)
+			seen[k]++
+			m.Delete(k)
+			mustValidate(t, m)
+			return true
+		})
+
+		isEqual(t, len(seen), n)
+		for k, n := range seen {
+			if n != 1 {
+				t.Fatalf("key %d has been seen %d times", k, n)
+			}
+		}
+		isEqual(t, m.Len(), 0)
+	})
+
+	t.Run("iteration started during shrinking", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < n; i++ {
+			m.Put(i, i)
+		}
+		i := 0
+		for ; !m.isShrinking(); i++ {
+			m.Delete(i)
+		}
+
+		seen := make(map[int]int, n)
+		m.Range(func(k, v int) bool {
+			isEqual(t, v, k)
+			seen[k]++
+			return true
+		})
+
+		isEqual(t, len(seen), n-i)
+		for k := i; k < n; k++ {
+			isEqual(t, seen[k], 1)
+		}
+	})
+}
+
+func TestClear(t *testing.T) {
+	t.Run("keeps buckets", func(t *testing.T) {
+		m := New[int, int](100).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+		buckets := &m.buckets[0]
+		B := m.B
+
+		m.Clear()
+		mustValidate(t, m)
+		isEqual(t, m.Len(), 0)
+		isEqual(t, m.B, B)
+		isEqual(t, &m.buckets[0] == buckets, true)
+		isEqual(t, m.noverflow, uint32(0))
+		for i := range m.buckets {
+			isEqual(t, m.buckets[i].overflow == nil, true)
+		}
+		for i := 0; i < 100; i++ {
+			_, ok := m.Get2(i)
+			isEqual(t, ok, false)
+		}
+
+		m.Put(1, 1)
+		isEqual(t, m.Get(1), 1)
+		isEqual(t, m.Len(), 1)
+	})
+
+	t.Run("cancels growth", func(t *testing.T) {
+		m, _ := newWithOverflowBuckets(t)
+		m.Put(-1, -1)
+		isEqual(t, m.isGrowing(), true)
+
+		m.Clear()
+		mustValidate(t, m)
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.sameSizeGrow(), false)
+		isEqual(t, m.numEvacuated, uint64(0))
+		isEqual(t, m.B, uint8(2))
+		isEqual(t, m.String(), "go-map[]")
+	})
+
+	t.Run("during iteration", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+
+		n := 0
+		m.Range(func(k, v int) bool {
+			n++
+			m.Clear()
+			return true
+		})
+		isEqual(t, n, 1)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+
+		m.Reset(1000)
+		mustValidate(t, m)
+		isEqual(t, m.Len(), 0)
+		isEqual(t, m.B, New[int, int](1000).(*hmap[int, int]).B)
+		for i := 0; i < 1000; i++ {
+			m.Put(i, i)
+		}
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.Get(999), 999)
+	})
+}
+
+// tophashStates returns tophash states of the bucket chain:
+// x - filled cell, 1 - emptyCell, 0 - emptyRest. buckets are separated with |
+func tophashStates[K comparable, V any](b *bucket[K, V]) string {
+	states := strings.Builder{}
+	for ; b != nil; b = b.overflow {
+		for _, top := range b.tophash {
+			switch top {
+			case emptyRest:
+				states.WriteByte('0')
+			case emptyCell:
+				states.WriteByte('1')
+			default:
+				states.WriteByte('x')
+			}
+		}
+		if b.overflow != nil {
+			states.WriteByte('|')
+		}
+	}
+	return states.String()
+}
+
+func TestEmptyRest(t *testing.T) {
+	type op struct {
+		put   bool
+		key   int // index of the key
+		state string
+	}
+
+	tests := []struct {
+		name string
+		ops  []op
+	}{
+		{
+			name: "delete the last cell",
+			ops: []op{
+				{put: true, key: 0, state: "x0000000"},
+				{put: true, key: 1, state: "xx000000"},
+				{key: 1, state: "x0000000"},
+				{key: 0, state: "00000000"},
+			},
+		},
+		{
+			name: "delete in the middle",
+			ops: []op{
+				{put: true, key: 0, state: "x0000000"},
+				{put: true, key: 1, state: "xx000000"},
+				{put: true, key: 2, state: "xxx00000"},
+				{key: 1, state: "x1x00000"},
+				{key: 0, state: "11x00000"},
+				{key: 2, state: "00000000"},
+			},
+		},
+		{
+			name: "put into a deleted cell",
+			ops: []op{
+				{put: true, key: 0, state: "x0000000"},
+				{put: true, key: 1, state: "xx000000"},
+				{put: true, key: 2, state: "xxx00000"},
+				{key: 0, state: "1xx00000"},
+				{put: true, key: 3, state: "xxx00000"},
+				{key: 2, state: "xx000000"},
+			},
+		},
+		{
+			name: "overflow bucket",
+			ops: []op{
+				{put: true, key: 0}, {put: true, key: 1}, {put: true, key: 2}, {put: true, key: 3},
+				{put: true, key: 4}, {put: true, key: 5}, {put: true, key: 6}, {put: true, key: 7},
+				{put: true, key: 8, state: "xxxxxxxx|x0000000"},
+				{put: true, key: 9, state: "xxxxxxxx|xx000000"},
+				{key: 6, state: "xxxxxx1x|xx000000"},
+				{key: 7, state: "xxxxxx11|xx000000"},
+				{key: 8, state: "xxxxxx11|1x000000"},
+				// the whole overflow bucket becomes empty and the state goes to the first bucket
+				{key: 9, state: "xxxxxx00|00000000"},
+				{put: true, key: 10, state: "xxxxxxx0|00000000"},
+				{key: 0, state: "1xxxxxx0|00000000"},
+				{key: 10, state: "1xxxxx00|00000000"},
+			},
+		},
+		{
+			name: "stop at a filled cell in an overflow bucket",
+			ops: []op{
+				{put: true, key: 0}, {put: true, key: 1}, {put: true, key: 2}, {put: true, key: 3},
+				{put: true, key: 4}, {put: true, key: 5}, {put: true, key: 6}, {put: true, key: 7},
+				{put: true, key: 8}, {put: true, key: 9}, {put: true, key: 10},
+				{key: 9, state: "xxxxxxxx|x1x00000"},
+				{key: 10, state: "xxxxxxxx|x0000000"},
+				{key: 7, state: "xxxxxxx1|x0000000"},
+				{key: 8, state: "xxxxxxx0|00000000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New[int, int](20).(*hmap[int, int])
+			keys := keysForBucket(m, 0, 11, 0)
+
+			for _, op := range tt.ops {
+				if op.put {
+					m.Put(keys[op.key], op.key)
+				} else {
+					m.Delete(keys[op.key])
+				}
+				mustValidate(t, m)
+
+				if op.state != "" {
+					isEqual(t, tophashStates(&m.buckets[0]), op.state)
+				}
+			}
+
+			// all the other keys are still accessible
+			n := 0
+			for i, k := range keys {
+				if v, ok := m.Get2(k); ok {
+					isEqual(t, v, i)
+					n++
+				}
+			}
+			isEqual(t, m.Len(), n)
+		})
+	}
+}
+
+func TestHasher(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		identity := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+		m := NewWithOptions(100, WithHasher[int, int](identity)).(*hmap[int, int])
+
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+			_, _, b := m.locateBucket(i)
+			isEqual(t, b, uint64(i)&bucketMask(m.B))
+		}
+		for i := 0; i < 100; i++ {
+			isEqual(t, m.Get(i), i)
+		}
+	})
+
+	t.Run("collisions", func(t *testing.T) {
+		constant := HasherFunc[string](func(string) uint64 { return 42 })
+		m := NewWithOptions(0, WithHasher[string, int](constant)).(*hmap[string, int])
+
+		n := 100
+		for i := 0; i < n; i++ {
+			m.Put(fmt.Sprintf("k%d", i), i)
+		}
+		for i := 0; i < n; i += 2 {
+			m.Delete(fmt.Sprintf("k%d", i))
+		}
+
+		isEqual(t, m.Len(), n/2)
+		for i := 0; i < n; i++ {
+			v, ok := m.Get2(fmt.Sprintf("k%d", i))
+			isEqual(t, ok, i%2 == 1)
+			if ok {
+				isEqual(t, v, i)
+			}
+		}
+		isEqual(t, len(maps.Collect(m.All())), n/2)
+	})
+}
+
+func TestPop(t *testing.T) {
+	m := New[string, int](8).(*hmap[string, int])
+	m.Put("a", 1)
+	m.Put("b", 2)
+
+	v, ok := m.Pop("a")
+	isEqual(t, v, 1)
+	isEqual(t, ok, true)
+	isEqual(t, m.Len(), 1)
+	_, ok = m.Get2("a")
+	isEqual(t, ok, false)
+
+	v, ok = m.Pop("a")
+	isEqual(t, v, 0)
+	isEqual(t, ok, false)
+	isEqual(t, m.Len(), 1)
+}
+
+func TestDeleteZeroesCells(t *testing.T) {
+	m := New[*int, *string](20).(*hmap[*int, *string])
+
+	keys := make([]*int, 3*bucketSize)
+	for i := range keys {
+		k, v := i, fmt.Sprint(i)
+		keys[i] = &k
+		m.Put(&k, &v)
+	}
+
+	for i, k := range keys {
+		if i%2 == 0 {
+			m.Delete(k)
+		} else {
+			m.Pop(k)
+		}
+	}
+
+	for i := range m.buckets {
+		for b := &m.buckets[i]; b != nil; b = b.overflow {
+			for j := range b.keys {
+				if b.keys[j] != nil || b.values[j] != nil {
+					t.Fatalf("deleted cell %d of bucket %d isn't zeroed", j, i)
+				}
+			}
+		}
+	}
+}
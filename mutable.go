@@ -0,0 +1,43 @@
+package gomap
+
+// MutableRanger - implemented by maps which can change elements in place during an iteration.
+//
+//	m.(gomap.MutableRanger[string, int]).RangeMutable(func(k string, v *int) (keep, cont bool) {
+//		*v *= 2
+//		return *v < 100, true
+//	})
+type MutableRanger[K comparable, V any] interface {
+	RangeMutable(f func(k K, v *V) (keep, cont bool))
+}
+
+var _ MutableRanger[string, int] = (*hmap[string, int])(nil)
+
+// RangeMutable - iterates through the map like Range, f gets a pointer to the value in its cell,
+// so the value is changed without hashing the key. If f returns keep == false the element is deleted
+// from its cell. If f returns cont == false, the loop breaks.
+// The map is marked as being written while f runs, so f must not access the map.
+// If f panics, the map stays usable.
+// Values of NaN keys whose buckets have been evacuated during the iteration are changed in a copy,
+// such NaN keys can't be deleted.
+func (m *hmap[K, V]) RangeMutable(f func(k K, v *V) (keep, cont bool)) {
+	for it := iterInit(m); it.key != nil && it.elem != nil; it.next() {
+		if !m.mutate(it, f) {
+			return
+		}
+	}
+}
+
+// mutate - calls f for the current element of the iteration
+// and deletes the element if f returns keep == false. returns cont from f
+func (m *hmap[K, V]) mutate(it *hiter[K, V], f func(k K, v *V) (keep, cont bool)) bool {
+	m.startWriting()
+	defer m.finishWriting()
+
+	keep, cont := f(*it.key, it.elem)
+	if !keep && it.cellBkt != nil {
+		it.cellHead.deleteAt(it.cellBkt, it.cellIdx)
+		m.deleted()
+	}
+
+	return cont
+}
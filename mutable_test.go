@@ -0,0 +1,203 @@
+package gomap
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRangeMutable(t *testing.T) {
+	t.Run("update and delete", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+
+		m.RangeMutable(func(k int, v *int) (bool, bool) {
+			*v *= 2
+			return k%2 == 0, true
+		})
+
+		isEqual(t, m.Len(), 50)
+		for i := 0; i < 100; i++ {
+			v, ok := m.Get2(i)
+			isEqual(t, ok, i%2 == 0)
+			if ok {
+				isEqual(t, v, i*2)
+			}
+		}
+		mustValidate(t, m)
+	})
+
+	t.Run("break", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 10; i++ {
+			m.Put(i, i)
+		}
+
+		calls := 0
+		m.RangeMutable(func(k int, v *int) (bool, bool) {
+			calls++
+			return false, calls < 3
+		})
+		isEqual(t, calls, 3)
+		isEqual(t, m.Len(), 7)
+	})
+
+	t.Run("map access inside f", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		m.Put(1, 1)
+
+		defer func() {
+			isEqual(t, recover(), "concurrent map access and write")
+		}()
+		m.RangeMutable(func(k int, v *int) (bool, bool) {
+			m.Get(k)
+			return true, true
+		})
+	})
+
+	t.Run("deletes in place", func(t *testing.T) {
+		if validateWrites {
+			t.Skip("the invariants are checked after every write, it hashes all the keys")
+		}
+		hashes := 0
+		hasher := HasherFunc[int](func(k int) uint64 {
+			hashes++
+			return uint64(k)
+		})
+		m := NewWithOptions(100, WithHasher[int, int](hasher)).(*hmap[int, int])
+		for i := 0; i < 100; i++ {
+			m.Put(i, i)
+		}
+
+		hashes = 0
+		m.RangeMutable(func(k int, v *int) (bool, bool) {
+			return k%2 == 0, true
+		})
+		isEqual(t, hashes, 0)
+		isEqual(t, m.Len(), 50)
+		mustValidate(t, m)
+	})
+
+	t.Run("NaN keys", func(t *testing.T) {
+		m := New[float64, int](0).(*hmap[float64, int])
+		nan := math.NaN()
+		m.Put(nan, 1)
+		m.Put(nan, 2)
+		m.Put(1, 1)
+
+		m.RangeMutable(func(k float64, v *int) (bool, bool) {
+			return k == k, true
+		})
+		isEqual(t, m.Len(), 1)
+		isEqual(t, m.Get(1), 1)
+	})
+
+	t.Run("panicking f", func(t *testing.T) {
+		for _, opts := range [][]Option[int, int]{nil, {WithAccessChecks[int, int]()}} {
+			m := NewWithOptions(0, opts...).(*hmap[int, int])
+			for i := 0; i < 10; i++ {
+				m.Put(i, i)
+			}
+
+			mustPanic(t, "boom", func() {
+				m.RangeMutable(func(k int, v *int) (bool, bool) { panic("boom") })
+			})
+
+			// the map isn't left marked as being written
+			m.Put(10, 10)
+			isEqual(t, m.Len(), 11)
+			isEqual(t, m.Get(10), 10)
+			m.RangeMutable(func(k int, v *int) (bool, bool) { return k != 10, true })
+			isEqual(t, m.Len(), 10)
+		}
+	})
+
+	t.Run("shrinking started by deletes", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < 1000; i++ {
+			m.Put(i, i)
+		}
+		B := m.B
+
+		// the deletes start shrinking, the elements met after that
+		// have been moved from the buckets the iteration started with
+		seen := map[int]bool{}
+		grown := false
+		m.RangeMutable(func(k int, v *int) (bool, bool) {
+			if seen[k] {
+				t.Fatalf("key %d is produced twice", k)
+			}
+			seen[k] = true
+			grown = grown || m.isGrowing()
+
+			*v = -k
+			return k%10 == 0, true
+		})
+
+		isEqual(t, grown, true)
+		isEqual(t, len(seen), 1000)
+		isEqual(t, m.Len(), 100)
+		for i := 0; i < 1000; i += 10 {
+			isEqual(t, m.Get(i), -i)
+		}
+		m.FinishGrowth()
+		if m.B >= B {
+			t.Fatalf("B is %d, the map hasn't shrunk from %d", m.B, B)
+		}
+		mustValidate(t, m)
+	})
+}
+
+// TestRangeMutableGrowthStates - starts RangeMutable after every evacuated old bucket
+func TestRangeMutableGrowthStates(t *testing.T) {
+	const n = 50
+
+	for _, kind := range []struct {
+		name string
+		grow func(m *hmap[int, int])
+	}{
+		{"bigger", func(m *hmap[int, int]) { m.ForceGrow(false) }},
+		{"same size", func(m *hmap[int, int]) { m.ForceGrow(true) }},
+		{"shrinking", func(m *hmap[int, int]) { m.startShrink() }},
+	} {
+		for step := 0; ; step++ {
+			m := New[int, int](100).(*hmap[int, int])
+			for i := 0; i < n; i++ {
+				m.Put(i, i)
+			}
+			kind.grow(m)
+			m.EvacuateStep(step)
+			if !m.IsGrowing() {
+				break
+			}
+
+			t.Run(fmt.Sprintf("%s/%d evacuated", kind.name, step), func(t *testing.T) {
+				seen := map[int]bool{}
+				m.RangeMutable(func(k int, v *int) (bool, bool) {
+					if seen[k] {
+						t.Fatalf("key %d is produced twice", k)
+					}
+					seen[k] = true
+					*v = -k
+					return k%3 != 0, true
+				})
+				isEqual(t, len(seen), n)
+				mustValidate(t, m)
+
+				want := map[int]int{}
+				for i := 0; i < n; i++ {
+					if i%3 != 0 {
+						want[i] = -i
+					}
+				}
+				checkContents(t, m, want)
+
+				m.FinishGrowth()
+				mustValidate(t, m)
+				checkContents(t, m, want)
+			})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package gomap
+
+import "cmp"
+
+// Option - configures a map created by NewWithOptions
+type Option[K comparable, V any] func(*options[K, V])
+
+type options[K comparable, V any] struct {
+	hasher     Hasher[K]
+	checked    bool
+	keyCodec   Codec[K]
+	valueCodec Codec[V]
+	sortedJSON bool
+	// String sorts keys by it, nil for the iteration order
+	stringLess func(a, b K) bool
+	// iterations start at the first bucket instead of a random one
+	deterministic bool
+}
+
+func newOptions[K comparable, V any](opts []Option[K, V]) options[K, V] {
+	o := options[K, V]{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.hasher == nil {
+		o.hasher = defaultHasher[K]()
+	}
+	if o.keyCodec == nil {
+		o.keyCodec = GobCodec[K]{}
+	}
+	if o.valueCodec == nil {
+		o.valueCodec = GobCodec[V]{}
+	}
+
+	return o
+}
+
+// WithHasher - sets the hash function for keys.
+// By default Go's runtime hasher with a random seed is used.
+func WithHasher[K comparable, V any](hasher Hasher[K]) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.hasher = hasher
+	}
+}
+
+// WithAccessChecks - enables the checked mode. Reads, writes and iterations
+// mark the map with atomic operations, so concurrent misuse panics
+// as soon as two accesses overlap instead of corrupting the map.
+// It makes every operation slower, use it for tests and debugging.
+func WithAccessChecks[K comparable, V any]() Option[K, V] {
+	return func(o *options[K, V]) {
+		o.checked = true
+	}
+}
+
+// WithCodecs - sets the codecs for keys and values used by MarshalBinary and UnmarshalBinary.
+// By default GobCodec is used for both.
+func WithCodecs[K comparable, V any](keys Codec[K], values Codec[V]) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.keyCodec = keys
+		o.valueCodec = values
+	}
+}
+
+// WithSortedJSON - makes MarshalJSON write keys in sorted order like encoding/json does for std maps.
+// By default keys are written in the iteration order, which starts at a random bucket.
+func WithSortedJSON[K comparable, V any]() Option[K, V] {
+	return func(o *options[K, V]) {
+		o.sortedJSON = true
+	}
+}
+
+// WithSortedString - makes String print keys in ascending order, NaNs go first like in cmp.Compare.
+// By default keys are printed in the iteration order, which starts at a random bucket.
+func WithSortedString[K cmp.Ordered, V any]() Option[K, V] {
+	return func(o *options[K, V]) {
+		o.stringLess = cmp.Less[K]
+	}
+}
+
+// WithDeterministicIteration - makes iterations start at the first bucket and the first cell
+// instead of random ones, so the order depends only on the hashes and the history of the map.
+// Use it with WithHasher with a fixed seed to get the same order between runs.
+func WithDeterministicIteration[K comparable, V any]() Option[K, V] {
+	return func(o *options[K, V]) {
+		o.deterministic = true
+	}
+}
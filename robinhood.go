@@ -0,0 +1,502 @@
+package gomap
+
+import (
+	"iter"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+)
+
+// Robin Hood hashing - open addressing with linear probing where an element
+// which is further from its home slot takes the slot from an element which is closer to its one
+// ("takes from the rich, gives to the poor"). It keeps probe sequences short and even.
+// On delete the following elements are shifted back instead of leaving tombstones.
+
+const (
+	// Maximum load of the table is 85%.
+	rhLoadNum = 85
+	rhLoadDen = 100
+)
+
+// robinHoodMap - Robin Hood hashing implementation of Hashmap.
+type robinHoodMap[K comparable, V any] struct {
+	entries []rhEntry[K, V]
+	len     int
+	mask    uint64 // # of slots - 1
+
+	// number of iterators which use the current entries.
+	// the entries are copied before a write if it isn't zero,
+	// so elements aren't moved under iterators.
+	// iterations are reads and may run concurrently, so it's changed atomically
+	iterators atomic.Int32
+
+	hasher        Hasher[K]
+	guard         *accessGuard // detects concurrent access in the checked mode, nil otherwise
+	flags         uint8
+	deterministic bool              // iterations start at the first slot
+	stringLess    func(a, b K) bool // String sorts keys by it, nil for the iteration order
+}
+
+type rhEntry[K comparable, V any] struct {
+	hash  uint64
+	dist  uint32 // distance from the home slot + 1, 0 for an empty slot
+	key   K
+	value V
+}
+
+// ProbeStats - probe length statistics of a Robin Hood table.
+// probe length is the distance from the slot an element is stored in to its home slot.
+type ProbeStats struct {
+	Len       int     // number of elements
+	Slots     int     // number of slots
+	MaxProbe  int     // the longest probe length
+	MeanProbe float64 // average probe length
+	// Histogram[i] is the number of elements with probe length i
+	Histogram []int
+}
+
+// ProbeStatsReporter - implemented by open addressing maps which report their probe lengths.
+//
+//	stats := gomap.NewRobinHood[string, int](100).(gomap.ProbeStatsReporter).ProbeStats()
+type ProbeStatsReporter interface {
+	ProbeStats() ProbeStats
+}
+
+// NewRobinHood - creates a new Robin Hood table for <size> elements
+func NewRobinHood[K comparable, V any](size int, opts ...Option[K, V]) Hashmap[K, V] {
+	o := newOptions(opts)
+	m := &robinHoodMap[K, V]{hasher: o.hasher, deterministic: o.deterministic, stringLess: o.stringLess}
+	if o.checked {
+		m.guard = new(accessGuard)
+	}
+	m.entries = make([]rhEntry[K, V], slotsFor(size))
+	m.mask = uint64(len(m.entries) - 1)
+
+	return m
+}
+
+// slotsFor returns the number of slots(a power of two) for <size> elements
+func slotsFor(size int) int {
+	n := 8
+	for n*rhLoadNum/rhLoadDen < size {
+		n <<= 1
+	}
+	return n
+}
+
+func (m *robinHoodMap[K, V]) Get(key K) V {
+	v, _ := m.Get2(key)
+	return v
+}
+
+func (m *robinHoodMap[K, V]) Get2(key K) (V, bool) {
+	if m.guard != nil {
+		m.guard.startReading("concurrent map read and map write")
+		defer m.guard.finishReading()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map access and write")
+	}
+
+	hash := m.hasher.Hash(key)
+	if i, _, found := m.find(key, hash); found {
+		return m.entries[i].value, true
+	}
+	return *new(V), false
+}
+
+// find - looks for the slot with the given key.
+// if the key doesn't exist, returns the slot where it should be put
+// and the distance from its home slot to this slot + 1.
+func (m *robinHoodMap[K, V]) find(key K, hash uint64) (i uint64, dist uint32, found bool) {
+	i = hash & m.mask
+	for dist = 1; ; dist++ {
+		e := &m.entries[i]
+		// the key would have taken this slot if it had existed
+		if e.dist < dist {
+			return i, dist, false
+		}
+		if e.hash == hash && e.key == key {
+			return i, dist, true
+		}
+		i = (i + 1) & m.mask
+	}
+}
+
+// prepareWrite - hashes the key and finds its slot.
+// the table grows first if a new key exceeds the load factor.
+func (m *robinHoodMap[K, V]) prepareWrite(key K) (hash uint64, i uint64, dist uint32, found bool) {
+	hash = m.hasher.Hash(key)
+	i, dist, found = m.find(key, hash)
+	i, dist = m.prepareSlot(key, hash, i, dist, found)
+	return hash, i, dist, found
+}
+
+// prepareSlot - detaches iterators before the slot <i> returned by find is written.
+// the table grows first if a new key exceeds the load factor, then the new slot is returned.
+func (m *robinHoodMap[K, V]) prepareSlot(key K, hash uint64, i uint64, dist uint32, found bool) (uint64, uint32) {
+	m.detachIterators()
+	if !found && (m.len+1)*rhLoadDen > len(m.entries)*rhLoadNum {
+		m.resize(len(m.entries) * 2)
+		i, dist, _ = m.find(key, hash)
+	}
+	return i, dist
+}
+
+// detachIterators - copies the entries if they are used by iterators
+func (m *robinHoodMap[K, V]) detachIterators() {
+	if m.iterators.Load() > 0 {
+		m.entries = slices.Clone(m.entries)
+		m.iterators.Store(0)
+	}
+}
+
+// insertAt - puts a new element into the slot <i> returned by find.
+// the elements which are closer to their home slots are shifted forward.
+func (m *robinHoodMap[K, V]) insertAt(i uint64, e rhEntry[K, V]) {
+	for {
+		cur := &m.entries[i]
+		if cur.dist == 0 {
+			*cur = e
+			break
+		}
+		// the current element is "richer", it gives the slot away
+		if cur.dist < e.dist {
+			*cur, e = e, *cur
+		}
+		i = (i + 1) & m.mask
+		e.dist++
+	}
+	m.len++
+}
+
+// deleteAt - deletes the element in the slot <i>.
+// the following elements are shifted back until an empty slot
+// or an element in its home slot, so there are no tombstones.
+func (m *robinHoodMap[K, V]) deleteAt(i uint64) {
+	for {
+		next := (i + 1) & m.mask
+		if m.entries[next].dist <= 1 {
+			break
+		}
+		m.entries[i] = m.entries[next]
+		m.entries[i].dist--
+		i = next
+	}
+	// zero the key and the value, so the GC doesn't keep objects they point to
+	m.entries[i] = rhEntry[K, V]{}
+	m.len--
+}
+
+// resize - moves all the elements into <n> new slots
+func (m *robinHoodMap[K, V]) resize(n int) {
+	old := m.entries
+	m.entries = make([]rhEntry[K, V], n)
+	m.mask = uint64(n - 1)
+	m.len = 0
+	m.iterators.Store(0)
+
+	for _, e := range old {
+		if e.dist == 0 {
+			continue
+		}
+		i := e.hash & m.mask
+		e.dist = 1
+		// hashes are stored, keys are unique, so the key doesn't need to be looked up
+		for m.entries[i].dist >= e.dist {
+			i = (i + 1) & m.mask
+			e.dist++
+		}
+		m.insertAt(i, e)
+	}
+}
+
+func (m *robinHoodMap[K, V]) Put(key K, value V) {
+	m.startWriting()
+
+	hash, i, dist, found := m.prepareWrite(key)
+	m.putAt(i, found, rhEntry[K, V]{hash: hash, dist: dist, key: key, value: value})
+
+	m.finishWriting()
+}
+
+func (m *robinHoodMap[K, V]) putAt(i uint64, found bool, e rhEntry[K, V]) {
+	if found {
+		m.entries[i].value = e.value
+		return
+	}
+	m.insertAt(i, e)
+}
+
+func (m *robinHoodMap[K, V]) Delete(key K) {
+	m.Pop(key)
+}
+
+func (m *robinHoodMap[K, V]) Pop(key K) (value V, ok bool) {
+	m.startWriting()
+
+	i, _, found := m.find(key, m.hasher.Hash(key))
+	if found {
+		m.detachIterators()
+		value, ok = m.entries[i].value, true
+		m.deleteAt(i)
+	}
+
+	m.finishWriting()
+	return value, ok
+}
+
+func (m *robinHoodMap[K, V]) Update(key K, f func(old V, ok bool) (V, bool)) {
+	m.startWriting()
+	// f may panic, the map must stay usable
+	defer m.finishWriting()
+
+	// iterators are detached and the table grows only if something is written
+	hash := m.hasher.Hash(key)
+	i, dist, found := m.find(key, hash)
+	var old V
+	if found {
+		old = m.entries[i].value
+	}
+	if v, ok := f(old, found); ok {
+		i, dist = m.prepareSlot(key, hash, i, dist, found)
+		m.putAt(i, found, rhEntry[K, V]{hash: hash, dist: dist, key: key, value: v})
+	}
+}
+
+func (m *robinHoodMap[K, V]) GetOrPut(key K, value V) (actual V, loaded bool) {
+	m.startWriting()
+
+	hash := m.hasher.Hash(key)
+	i, dist, found := m.find(key, hash)
+	if found {
+		actual = m.entries[i].value
+	} else {
+		actual = value
+		i, dist = m.prepareSlot(key, hash, i, dist, found)
+		m.insertAt(i, rhEntry[K, V]{hash: hash, dist: dist, key: key, value: value})
+	}
+
+	m.finishWriting()
+	return actual, found
+}
+
+func (m *robinHoodMap[K, V]) Compute(key K, f func(old V, ok bool) (V, bool)) (actual V, ok bool) {
+	m.startWriting()
+	// f may panic, the map must stay usable
+	defer m.finishWriting()
+
+	// iterators are detached and the table grows only if something is written
+	hash := m.hasher.Hash(key)
+	i, dist, found := m.find(key, hash)
+	var old V
+	if found {
+		old = m.entries[i].value
+	}
+	actual, ok = f(old, found)
+	if ok {
+		i, dist = m.prepareSlot(key, hash, i, dist, found)
+		m.putAt(i, found, rhEntry[K, V]{hash: hash, dist: dist, key: key, value: actual})
+	} else {
+		if found {
+			m.detachIterators()
+			m.deleteAt(i)
+		}
+		actual = *new(V)
+	}
+
+	return actual, ok
+}
+
+func (m *robinHoodMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	m.startWriting()
+
+	hash, i, dist, found := m.prepareWrite(key)
+	if found {
+		previous = m.entries[i].value
+	}
+	m.putAt(i, found, rhEntry[K, V]{hash: hash, dist: dist, key: key, value: value})
+
+	m.finishWriting()
+	return previous, found
+}
+
+// Range - iterates through the slots starting from a random one.
+// elements are moved by inserts and deletes, so the entries are copied
+// on the first write during the iteration. the iterator keeps the old ones
+// and looks values up in the new entries.
+func (m *robinHoodMap[K, V]) Range(f func(k K, v V) bool) {
+	if m.len == 0 {
+		return
+	}
+
+	entries := m.entries
+	m.iterators.Add(1)
+	defer func() {
+		if &entries[0] == &m.entries[0] {
+			m.iterators.Add(-1)
+		}
+	}()
+
+	mask := uint64(len(entries) - 1)
+	start := uint64(0)
+	if !m.deterministic {
+		start = rand.Uint64()
+	}
+	for n := uint64(0); n <= mask; n++ {
+		key, value, ok := m.iterSlot(entries, (start+n)&mask)
+		if !ok {
+			continue
+		}
+		if !f(key, value) {
+			return
+		}
+	}
+}
+
+// iterSlot - reads the slot <i> of the entries for Range. returns false if there is no element.
+// like hiter.next, the slot is read as a map read, so concurrent writes are detected in the checked mode
+func (m *robinHoodMap[K, V]) iterSlot(entries []rhEntry[K, V], i uint64) (key K, value V, ok bool) {
+	if m.guard != nil {
+		m.guard.startReading("concurrent map iteration and map write")
+		defer m.guard.finishReading()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map iteration and map write")
+	}
+
+	e := &entries[i]
+	if e.dist == 0 {
+		return key, value, false
+	}
+
+	key, value = e.key, e.value
+	if &entries[0] != &m.entries[0] && key == key {
+		// the map has been changed, the key could be updated or deleted.
+		// NaNs can't be looked up, the old value is returned for them
+		value, ok = m.Get2(key)
+		return key, value, ok
+	}
+	return key, value, true
+}
+
+func (m *robinHoodMap[K, V]) RangeSorted(less func(a, b K) bool, f func(k K, v V) bool) {
+	rangeSorted(m.Range, less, f)
+}
+
+func (m *robinHoodMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.Range(yield)
+	}
+}
+
+func (m *robinHoodMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+func (m *robinHoodMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+func (m *robinHoodMap[K, V]) Len() int {
+	return m.len
+}
+
+func (m *robinHoodMap[K, V]) Shrink() {
+	m.startWriting()
+	m.resize(slotsFor(m.len))
+	m.finishWriting()
+}
+
+// Compact - there are no tombstones in a Robin Hood table, so there is nothing to compact
+func (m *robinHoodMap[K, V]) Compact() {}
+
+// Clear - removes all the elements keeping allocated slots
+func (m *robinHoodMap[K, V]) Clear() {
+	m.startWriting()
+
+	// iterators of the current entries see empty slots,
+	// iterators of older ones don't find the keys in the cleared entries
+	clear(m.entries)
+	m.len = 0
+
+	m.finishWriting()
+}
+
+func (m *robinHoodMap[K, V]) Reset(size int) {
+	m.startWriting()
+
+	m.entries = make([]rhEntry[K, V], slotsFor(size))
+	m.mask = uint64(len(m.entries) - 1)
+	m.len = 0
+	m.iterators.Store(0)
+
+	m.finishWriting()
+}
+
+// ProbeStats - returns probe length statistics of the table
+func (m *robinHoodMap[K, V]) ProbeStats() ProbeStats {
+	stats := ProbeStats{Len: m.len, Slots: len(m.entries)}
+
+	total := 0
+	for _, e := range m.entries {
+		if e.dist == 0 {
+			continue
+		}
+		probe := int(e.dist - 1)
+		for len(stats.Histogram) <= probe {
+			stats.Histogram = append(stats.Histogram, 0)
+		}
+		stats.Histogram[probe]++
+		stats.MaxProbe = max(stats.MaxProbe, probe)
+		total += probe
+	}
+	if m.len > 0 {
+		stats.MeanProbe = float64(total) / float64(m.len)
+	}
+
+	return stats
+}
+
+func (m *robinHoodMap[K, V]) String() string {
+	return formatMap(m.Range, m.stringLess)
+}
+
+func (m *robinHoodMap[K, V]) startWriting() {
+	if m.guard != nil {
+		m.guard.startWriting()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map writes")
+	}
+	m.flags ^= hashWriting
+}
+
+func (m *robinHoodMap[K, V]) finishWriting() {
+	if m.flags&hashWriting == 0 {
+		panic("concurrent map writes")
+	}
+	m.flags &^= hashWriting
+	if m.guard != nil {
+		m.guard.finishWriting()
+	}
+}
+
+var (
+	_ Hashmap[string, int]      = (*robinHoodMap[string, int])(nil)
+	_ Sequencer[string, int]    = (*robinHoodMap[string, int])(nil)
+	_ Popper[string, int]       = (*robinHoodMap[string, int])(nil)
+	_ Upserter[string, int]     = (*robinHoodMap[string, int])(nil)
+	_ SortedRanger[string, int] = (*robinHoodMap[string, int])(nil)
+	_ Shrinker                  = (*robinHoodMap[string, int])(nil)
+	_ Clearer                   = (*robinHoodMap[string, int])(nil)
+	_ ProbeStatsReporter        = (*robinHoodMap[string, int])(nil)
+)
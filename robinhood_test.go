@@ -0,0 +1,242 @@
+package gomap
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+)
+
+func TestRobinHood(t *testing.T) {
+	m := NewRobinHood[string, int](0).(*robinHoodMap[string, int])
+
+	_, ok := m.Get2("a")
+	isEqual(t, ok, false)
+
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Put(fmt.Sprint(i), i)
+	}
+	isEqual(t, m.Len(), n)
+	for i := 0; i < n; i++ {
+		isEqual(t, m.Get(fmt.Sprint(i)), i)
+	}
+
+	m.Put("1", 100)
+	isEqual(t, m.Get("1"), 100)
+	isEqual(t, m.Len(), n)
+
+	for i := 0; i < n; i += 2 {
+		m.Delete(fmt.Sprint(i))
+	}
+	isEqual(t, m.Len(), n/2)
+	isEqual(t, len(maps.Collect(m.All())), n/2)
+
+	m.Shrink()
+	isEqual(t, len(m.entries), slotsFor(n/2))
+	for i := 1; i < n; i += 2 {
+		_, ok := m.Get2(fmt.Sprint(i))
+		isEqual(t, ok, true)
+	}
+
+	m.Clear()
+	isEqual(t, m.Len(), 0)
+	isEqual(t, m.String(), "go-map[]")
+}
+
+func TestRobinHoodBackwardShift(t *testing.T) {
+	// a key is hashed to its value, so the home slot of a key <= 7 is the key itself
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+	m := NewRobinHood(0, WithHasher[int, int](hasher)).(*robinHoodMap[int, int])
+
+	layout := func() (keys []int, dists []uint32) {
+		for _, e := range m.entries {
+			keys = append(keys, e.key)
+			dists = append(dists, e.dist)
+		}
+		return keys, dists
+	}
+
+	m.Put(1, 1)
+	m.Put(9, 9)   // home slot 1 is taken
+	m.Put(2, 2)   // home slot 2 is taken by 9, which is further from its home slot
+	m.Put(17, 17) // takes slot 3 from 2, which is closer to its home slot
+	keys, dists := layout()
+	isEqual(t, keys, []int{0, 1, 9, 17, 2, 0, 0, 0})
+	isEqual(t, dists, []uint32{0, 1, 2, 3, 3, 0, 0, 0})
+
+	// the elements after the deleted one are shifted back
+	m.Delete(1)
+	keys, dists = layout()
+	isEqual(t, keys, []int{0, 9, 17, 2, 0, 0, 0, 0})
+	isEqual(t, dists, []uint32{0, 1, 2, 2, 0, 0, 0, 0})
+
+	// 2 isn't shifted to its home slot, the shift stops at an element in its home slot
+	m.Put(3, 3)
+	m.Delete(17)
+	keys, dists = layout()
+	isEqual(t, keys, []int{0, 9, 2, 3, 0, 0, 0, 0})
+	isEqual(t, dists, []uint32{0, 1, 1, 1, 0, 0, 0, 0})
+
+	isEqual(t, m.Get(9), 9)
+	isEqual(t, m.Get(2), 2)
+	isEqual(t, m.Get(3), 3)
+}
+
+func TestRobinHoodProbeStats(t *testing.T) {
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+	m := NewRobinHood(0, WithHasher[int, int](hasher))
+
+	isEqual(t, m.(ProbeStatsReporter).ProbeStats(), ProbeStats{Slots: 8})
+
+	for _, k := range []int{1, 9, 17, 2} {
+		m.Put(k, k)
+	}
+	isEqual(t, m.(ProbeStatsReporter).ProbeStats(), ProbeStats{
+		Len:       4,
+		Slots:     8,
+		MaxProbe:  2,
+		MeanProbe: 1.25,
+		Histogram: []int{1, 1, 2},
+	})
+
+	r := NewRobinHood[int, int](0)
+	for i := 0; i < 10000; i++ {
+		r.Put(i, i)
+	}
+	stats := r.(ProbeStatsReporter).ProbeStats()
+	isEqual(t, stats.Len, 10000)
+	sum := 0
+	for _, n := range stats.Histogram {
+		sum += n
+	}
+	isEqual(t, sum, 10000)
+	isEqual(t, len(stats.Histogram), stats.MaxProbe+1)
+}
+
+func TestRobinHoodRangeDuringWrites(t *testing.T) {
+	m := NewRobinHood[int, int](0).(*robinHoodMap[int, int])
+	n := 50
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	// deletes shift elements back and puts shift them forward,
+	// the iteration must produce every element once anyway
+	seen := make(map[int]int, n)
+	deleted := make(map[int]bool, n)
+	next := n
+	m.Range(func(k, v int) bool {
+		if deleted[k] {
+			t.Fatalf("deleted key %d has been produced", k)
+		}
+		isEqual(t, v, k)
+		seen[k]++
+
+		m.Delete(k)
+		m.Put(k, k)
+		for i := 0; i < 5; i++ {
+			m.Put(next, next)
+			next++
+		}
+		if k < n && k%2 == 0 {
+			m.Delete(n - 1 - k)
+			deleted[n-1-k] = true
+		}
+		return true
+	})
+
+	for k, n := range seen {
+		if n != 1 {
+			t.Fatalf("key %d has been seen %d times", k, n)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !deleted[i] {
+			isEqual(t, seen[i], 1)
+		}
+	}
+	isEqual(t, m.iterators.Load(), int32(0))
+}
+
+// iterations are reads, so any number of them may run at the same time. run it with -race
+func TestRobinHoodConcurrentIterations(t *testing.T) {
+	n := 100
+	m := NewRobinHood[int, int](0).(*robinHoodMap[int, int])
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 20; round++ {
+				got := 0
+				m.Range(func(k, v int) bool {
+					got++
+					return true
+				})
+				if got != n {
+					t.Errorf("got %d elements, want %d", got, n)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every iteration has been unregistered
+	isEqual(t, m.iterators.Load(), int32(0))
+}
+
+func TestRobinHoodUpserts(t *testing.T) {
+	t.Run("no copy or resize without a write", func(t *testing.T) {
+		m := NewRobinHood[int, int](0).(*robinHoodMap[int, int])
+		n := 0
+		// the next new key makes the table grow
+		for (m.len+1)*rhLoadDen <= len(m.entries)*rhLoadNum {
+			m.Put(n, n)
+			n++
+		}
+
+		keep := func(old int, ok bool) (int, bool) { return old, ok }
+		m.Range(func(k, v int) bool {
+			// the iteration uses the entries, writes would copy them
+			entries := &m.entries[0]
+			m.Update(-1, keep)
+			m.Compute(-1, keep)
+			m.GetOrPut(0, 1)
+			isEqual(t, &m.entries[0] == entries, true)
+			isEqual(t, m.iterators.Load(), int32(1))
+
+			m.Update(0, keep)
+			isEqual(t, &m.entries[0] == entries, false)
+			return false
+		})
+		isEqual(t, m.Len(), n)
+		isEqual(t, m.Get(0), 0)
+
+		entries := &m.entries[0]
+		m.GetOrPut(-1, -1)
+		isEqual(t, &m.entries[0] == entries, false)
+		isEqual(t, m.Get(-1), -1)
+	})
+
+	t.Run("panicking callback", func(t *testing.T) {
+		for _, opts := range [][]Option[int, int]{nil, {WithAccessChecks[int, int]()}} {
+			m := NewRobinHood(0, opts...).(*robinHoodMap[int, int])
+			m.Put(1, 1)
+
+			boom := func(old int, ok bool) (int, bool) { panic("boom") }
+			mustPanic(t, "boom", func() { m.Update(1, boom) })
+			mustPanic(t, "boom", func() { m.Compute(2, boom) })
+
+			// the map isn't left marked as being written
+			m.Put(2, 2)
+			isEqual(t, m.Get(1), 1)
+			isEqual(t, m.Get(2), 2)
+			isEqual(t, m.Len(), 2)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package gomap
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SortedRanger - implemented by maps which can iterate in the order of keys.
+type SortedRanger[K comparable, V any] interface {
+	// iterates through a snapshot of the map in the order of <less>.
+	// writes inside f don't change the produced elements
+	RangeSorted(less func(a, b K) bool, f func(k K, v V) bool)
+}
+
+var _ SortedRanger[string, int] = (*hmap[string, int])(nil)
+
+// rangeSorted - copies the elements produced by <rangeFunc>, sorts them by <less>
+// and calls f for each of them
+func rangeSorted[K comparable, V any](rangeFunc func(f func(k K, v V) bool), less func(a, b K) bool, f func(k K, v V) bool) {
+	type entry struct {
+		key   K
+		value V
+	}
+
+	var entries []entry
+	rangeFunc(func(k K, v V) bool {
+		entries = append(entries, entry{k, v})
+		return true
+	})
+	slices.SortFunc(entries, func(a, b entry) int {
+		switch {
+		case less(a.key, b.key):
+			return -1
+		case less(b.key, a.key):
+			return 1
+		}
+		return 0
+	})
+
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// SortedString - formats the map like String, but with the keys in ascending order.
+// NaNs go first, like in cmp.Compare.
+// Maps created with WithSortedString print sorted keys from String itself.
+func SortedString[K cmp.Ordered, V any](m Hashmap[K, V]) string {
+	return formatMap(m.Range, cmp.Less[K])
+}
@@ -0,0 +1,105 @@
+package gomap
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDeterministicIteration(t *testing.T) {
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k) * 0x9E3779B97F4A7C15 })
+	for _, impl := range []struct {
+		name   string
+		newMap func() Hashmap[int, int]
+	}{
+		{"hmap", func() Hashmap[int, int] {
+			return NewWithOptions(0, WithHasher[int, int](hasher), WithDeterministicIteration[int, int]())
+		}},
+		{"swiss", func() Hashmap[int, int] {
+			return NewSwiss(0, WithHasher[int, int](hasher), WithDeterministicIteration[int, int]())
+		}},
+		{"robin hood", func() Hashmap[int, int] {
+			return NewRobinHood(0, WithHasher[int, int](hasher), WithDeterministicIteration[int, int]())
+		}},
+		{"concurrent", func() Hashmap[int, int] {
+			return NewConcurrent(0, 4, WithHasher[int, int](hasher), WithDeterministicIteration[int, int]())
+		}},
+	} {
+		t.Run(impl.name, func(t *testing.T) {
+			keys := func(m Hashmap[int, int]) []int {
+				var keys []int
+				m.Range(func(k, _ int) bool {
+					keys = append(keys, k)
+					return true
+				})
+				return keys
+			}
+
+			m := impl.newMap()
+			for i := 0; i < 100; i++ {
+				m.Put(i, i)
+			}
+			want := keys(m)
+			for i := 0; i < 10; i++ {
+				isEqual(t, keys(m), want)
+			}
+
+			// the same history gives the same order
+			other := impl.newMap()
+			for i := 0; i < 100; i++ {
+				other.Put(i, i)
+			}
+			isEqual(t, keys(other), want)
+		})
+	}
+
+	t.Run("bucket order", func(t *testing.T) {
+		identity := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+		m := NewWithOptions(16, WithHasher[int, int](identity), WithDeterministicIteration[int, int]())
+		for _, k := range []int{7, 3, 6, 2, 5, 1, 4, 0} {
+			m.Put(k, k)
+		}
+
+		var keys []int
+		m.Range(func(k, _ int) bool {
+			keys = append(keys, k)
+			return true
+		})
+		// B=2, the bucket is k%4, keys of a bucket are in the order of puts
+		isEqual(t, keys, []int{4, 0, 5, 1, 6, 2, 7, 3})
+	})
+}
+
+func TestSortedString(t *testing.T) {
+	m := New[string, int](0)
+	isEqual(t, SortedString(m), "go-map[]")
+	for i, k := range []string{"c", "a", "b"} {
+		m.Put(k, i)
+	}
+	isEqual(t, SortedString(m), "go-map[a:1 b:2 c:0]")
+
+	floats := NewSwiss[float64, int](0)
+	floats.Put(1.5, 1)
+	floats.Put(math.NaN(), 2)
+	floats.Put(-1, 3)
+	isEqual(t, SortedString(floats), "go-map[NaN:2 -1:3 1.5:1]")
+}
+
+func TestWithSortedString(t *testing.T) {
+	for name, m := range map[string]Hashmap[string, int]{
+		"hmap":       NewWithOptions(0, WithSortedString[string, int]()),
+		"swiss":      NewSwiss(0, WithSortedString[string, int]()),
+		"robin hood": NewRobinHood(0, WithSortedString[string, int]()),
+		"concurrent": NewConcurrent(0, 4, WithSortedString[string, int]()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			isEqual(t, m.String(), "go-map[]")
+			for i := 0; i < 20; i++ {
+				m.Put(fmt.Sprintf("k%02d", 19-i), i)
+			}
+			isEqual(t, m.String(), SortedString(m))
+			isEqual(t, strings.HasPrefix(m.String(), "go-map[k00:19 k01:18 k02:17 "), true)
+		})
+	}
+}
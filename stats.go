@@ -0,0 +1,108 @@
+package gomap
+
+import "unsafe"
+
+// Stats - the internal shape of a map created by New.
+type Stats struct {
+	Len int   // # of elements
+	B   uint8 // log_2 of # of buckets
+
+	Buckets         int // # of buckets in the main array
+	OverflowBuckets int // # of overflow buckets hanging off the main array
+
+	MaxChain  int     // the longest chain of a bucket and its overflow buckets
+	MeanChain float64 // average length of a chain
+	// average # of elements per bucket of the main array.
+	// the map grows when it reaches 6.5
+	LoadFactor float64
+	// # of elements which share their tophash with a previous element of the same chain.
+	// a lookup of such an element compares keys for each of them
+	TophashCollisions int
+
+	Growing      bool
+	SameSizeGrow bool
+	Shrinking    bool
+	// the evacuation progress: old buckets below Evacuated have been moved to the main array
+	Evacuated          uint64
+	OldBuckets         uint64 // # of old buckets, 0 if the map isn't growing
+	OldOverflowBuckets int
+
+	// estimated memory used by the map and all its buckets
+	MemoryBytes uintptr
+}
+
+// StatsReporter - implemented by maps which report their internal shape.
+//
+//	stats := gomap.New[string, int](100).(gomap.StatsReporter).Stats()
+type StatsReporter interface {
+	Stats() Stats
+}
+
+var _ StatsReporter = (*hmap[string, int])(nil)
+
+// Stats - returns the internal shape of the map. It works during growth as well.
+func (m *hmap[K, V]) Stats() Stats {
+	if m.guard != nil {
+		m.guard.startReading("concurrent map read and map write")
+		defer m.guard.finishReading()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map access and write")
+	}
+
+	s := Stats{
+		Len:          m.len,
+		B:            m.B,
+		Buckets:      len(m.buckets),
+		Growing:      m.isGrowing(),
+		SameSizeGrow: m.sameSizeGrow(),
+		Shrinking:    m.isShrinking(),
+	}
+
+	for i := range m.buckets {
+		chain, collisions := chainStats(&m.buckets[i])
+		s.OverflowBuckets += chain - 1
+		s.MaxChain = max(s.MaxChain, chain)
+		s.TophashCollisions += collisions
+	}
+	s.MeanChain = float64(s.Buckets+s.OverflowBuckets) / float64(s.Buckets)
+	s.LoadFactor = float64(m.len) / float64(s.Buckets)
+
+	if m.isGrowing() {
+		s.Evacuated = m.numEvacuated
+		s.OldBuckets = m.numOldBuckets()
+		for i := range *m.oldbuckets {
+			b := &(*m.oldbuckets)[i]
+			chain, collisions := chainStats(b)
+			s.OldOverflowBuckets += chain - 1
+			// evacuated elements are counted in the main array
+			if !b.isEvacuated() {
+				s.TophashCollisions += collisions
+			}
+		}
+	}
+
+	allBuckets := uintptr(s.Buckets+s.OverflowBuckets) + uintptr(s.OldBuckets) + uintptr(s.OldOverflowBuckets)
+	s.MemoryBytes = unsafe.Sizeof(*m) + allBuckets*unsafe.Sizeof(bucket[K, V]{})
+
+	return s
+}
+
+// chainStats returns the length of the bucket chain starting at <b>
+// and the number of its filled cells which repeat a tophash of a previous cell.
+func chainStats[K comparable, V any](b *bucket[K, V]) (chain, collisions int) {
+	var seen [256]bool
+	for ; b != nil; b = b.overflow {
+		chain++
+		for _, top := range b.tophash {
+			if top < minTopHash {
+				continue
+			}
+			if seen[top] {
+				collisions++
+			}
+			seen[top] = true
+		}
+	}
+	return chain, collisions
+}
@@ -0,0 +1,78 @@
+package gomap
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestStats(t *testing.T) {
+	// all small keys have the same tophash
+	identity := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+	m := NewWithOptions(20, WithHasher[int, int](identity)).(*hmap[int, int])
+	bucketSize := unsafe.Sizeof(bucket[int, int]{})
+	mapSize := unsafe.Sizeof(*m)
+
+	isEqual(t, m.Stats(), Stats{
+		B:           2,
+		Buckets:     4,
+		MaxChain:    1,
+		MeanChain:   1,
+		MemoryBytes: mapSize + 4*bucketSize,
+	})
+
+	// 20 keys of the bucket 0 make a chain of 3 buckets
+	for k := 0; k < 80; k += 4 {
+		m.Put(k, k)
+	}
+	m.Put(1, 1)
+	m.Put(2, 2)
+
+	isEqual(t, m.Stats(), Stats{
+		Len:               22,
+		B:                 2,
+		Buckets:           4,
+		OverflowBuckets:   2,
+		MaxChain:          3,
+		MeanChain:         1.5,
+		LoadFactor:        5.5,
+		TophashCollisions: 19,
+		MemoryBytes:       mapSize + 6*bucketSize,
+	})
+
+	t.Run("during growth", func(t *testing.T) {
+		m.moveToNewBuckets(m.B+1, 0)
+		m.evacuate(0)
+
+		// the keys of the old bucket 0 are split between the buckets 0 and 4
+		isEqual(t, m.Stats(), Stats{
+			Len:                22,
+			B:                  3,
+			Buckets:            8,
+			OverflowBuckets:    2,
+			MaxChain:           2,
+			MeanChain:          1.25,
+			LoadFactor:         2.75,
+			TophashCollisions:  18,
+			Growing:            true,
+			Evacuated:          1,
+			OldBuckets:         4,
+			OldOverflowBuckets: 2,
+			MemoryBytes:        mapSize + 16*bucketSize,
+		})
+
+		m.finishGrowth()
+		s := m.Stats()
+		isEqual(t, s.Growing, false)
+		isEqual(t, s.OldBuckets, uint64(0))
+		isEqual(t, s.Evacuated, uint64(0))
+		isEqual(t, s.MemoryBytes, mapSize+10*bucketSize)
+	})
+
+	t.Run("shrinking", func(t *testing.T) {
+		m.startShrink()
+		s := m.Stats()
+		isEqual(t, s.Shrinking, true)
+		isEqual(t, s.OldBuckets, uint64(8))
+		isEqual(t, s.B, uint8(2))
+	})
+}
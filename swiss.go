@@ -0,0 +1,547 @@
+package gomap
+
+import (
+	"iter"
+	"math/bits"
+	"math/rand"
+)
+
+// Swiss table - an open-addressing hashmap used by Go since 1.24.
+//
+// Slots are grouped by 8. Every group has a word of 8 control bytes, one per slot.
+// A control byte is either empty, deleted(a tombstone) or the low 7 bits of the key's hash(h2).
+// The rest of the hash(h1) selects the group where probing starts.
+// All 8 control bytes of a group are compared with h2 at once using
+// bit tricks on uint64 (SWAR - SIMD within a register), so only keys
+// whose h2 matches are compared.
+
+const (
+	groupSize = 8
+
+	ctrlEmpty   = 0b1000_0000
+	ctrlDeleted = 0b1111_1110
+
+	// Maximum average load of a group is 7/8, the same as in Go's swiss tables.
+	swissLoadNum = 7
+	swissLoadDen = 8
+
+	lsbs = 0x0101010101010101 // the lowest bit of each byte
+	msbs = 0x8080808080808080 // the highest bit of each byte
+)
+
+type swissMap[K comparable, V any] struct {
+	groups     []group[K, V]
+	len        int
+	tombstones int
+	growthLeft int // number of empty slots which can be filled before the table is rehashed
+
+	hasher        Hasher[K]
+	guard         *accessGuard // detects concurrent access in the checked mode, nil otherwise
+	flags         uint8
+	deterministic bool              // iterations start at the first group
+	stringLess    func(a, b K) bool // String sorts keys by it, nil for the iteration order
+}
+
+type group[K comparable, V any] struct {
+	ctrl   uint64 // control bytes, the byte i describes the slot i
+	keys   [groupSize]K
+	values [groupSize]V
+}
+
+// NewSwiss - creates a new swiss table for <size> elements
+func NewSwiss[K comparable, V any](size int, opts ...Option[K, V]) Hashmap[K, V] {
+	o := newOptions(opts)
+	m := &swissMap[K, V]{hasher: o.hasher, deterministic: o.deterministic, stringLess: o.stringLess}
+	if o.checked {
+		m.guard = new(accessGuard)
+	}
+	m.groups = newGroups[K, V](groupsFor(size))
+	m.growthLeft = maxLoad(len(m.groups))
+
+	return m
+}
+
+// groupsFor returns the number of groups(a power of two) for <size> elements
+func groupsFor(size int) int {
+	n := 1
+	for maxLoad(n) < size {
+		n <<= 1
+	}
+	return n
+}
+
+// maxLoad returns the number of slots which can be filled in <n> groups
+func maxLoad(n int) int {
+	return n * groupSize * swissLoadNum / swissLoadDen
+}
+
+func newGroups[K comparable, V any](n int) []group[K, V] {
+	groups := make([]group[K, V], n)
+	for i := range groups {
+		groups[i].ctrl = lsbs * ctrlEmpty
+	}
+	return groups
+}
+
+// splitHash returns the group where probing starts and 7 bits stored in the control byte
+func splitHash(hash uint64) (h1 uint64, h2 uint8) {
+	return hash >> 7, uint8(hash & 0x7f)
+}
+
+// probeSeq - triangular probing over groups: offset, offset+1, offset+1+2, ...
+// it visits every group exactly once if the number of groups is a power of two.
+type probeSeq struct {
+	mask   uint64
+	offset uint64
+	index  uint64
+}
+
+func makeProbeSeq(h1 uint64, mask uint64) probeSeq {
+	return probeSeq{mask: mask, offset: h1 & mask}
+}
+
+func (s probeSeq) next() probeSeq {
+	s.index++
+	s.offset = (s.offset + s.index) & s.mask
+	return s
+}
+
+// bitset - the highest bit of a byte is set for each matched slot
+type bitset uint64
+
+func (b bitset) first() int {
+	return bits.TrailingZeros64(uint64(b)) / 8
+}
+
+func (b bitset) removeFirst() bitset {
+	return b & (b - 1)
+}
+
+// matchH2 returns slots whose control byte is equal to h2.
+// it may return false positives next to a real match, keys must be compared anyway.
+func (g *group[K, V]) matchH2(h2 uint8) bitset {
+	// bytes equal to h2 become zero, then the zero bytes are found:
+	// subtracting 1 borrows the highest bit only for a zero byte
+	x := g.ctrl ^ (lsbs * uint64(h2))
+	return bitset((x - lsbs) &^ x & msbs)
+}
+
+// matchEmpty returns empty slots. empty is 1000_0000, deleted is 1111_1110,
+// so a byte is empty if its highest bit is set and the bit 1 isn't.
+func (g *group[K, V]) matchEmpty() bitset {
+	return bitset((g.ctrl &^ (g.ctrl << 6)) & msbs)
+}
+
+// matchEmptyOrDeleted returns slots which don't hold a value
+func (g *group[K, V]) matchEmptyOrDeleted() bitset {
+	return bitset(g.ctrl & msbs)
+}
+
+func (g *group[K, V]) ctrlAt(i int) uint8 {
+	return uint8(g.ctrl >> (8 * i))
+}
+
+func (g *group[K, V]) setCtrl(i int, c uint8) {
+	g.ctrl = g.ctrl&^(0xff<<(8*i)) | uint64(c)<<(8*i)
+}
+
+func (m *swissMap[K, V]) Get(key K) V {
+	v, _ := m.Get2(key)
+	return v
+}
+
+func (m *swissMap[K, V]) Get2(key K) (V, bool) {
+	if m.guard != nil {
+		m.guard.startReading("concurrent map read and map write")
+		defer m.guard.finishReading()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map access and write")
+	}
+
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	for seq := makeProbeSeq(h1, uint64(len(m.groups)-1)); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		for match := g.matchH2(h2); match != 0; match = match.removeFirst() {
+			i := match.first()
+			if g.keys[i] == key {
+				return g.values[i], true
+			}
+		}
+
+		// the key would have been put into this group if it had existed
+		if g.matchEmpty() != 0 {
+			return *new(V), false
+		}
+	}
+}
+
+// find - looks for the slot with the given key.
+// if the key doesn't exist, returns the first empty or deleted slot on its probe sequence.
+func (m *swissMap[K, V]) find(key K, h1 uint64, h2 uint8) (g *group[K, V], i int, found bool) {
+	var insertGroup *group[K, V]
+	var insertIdx int
+
+	for seq := makeProbeSeq(h1, uint64(len(m.groups)-1)); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		for match := g.matchH2(h2); match != 0; match = match.removeFirst() {
+			i := match.first()
+			if g.keys[i] == key {
+				return g, i, true
+			}
+		}
+
+		if insertGroup == nil {
+			if match := g.matchEmptyOrDeleted(); match != 0 {
+				insertGroup, insertIdx = g, match.first()
+			}
+		}
+		if g.matchEmpty() != 0 {
+			return insertGroup, insertIdx, false
+		}
+	}
+}
+
+// prepareWrite - hashes the key and finds its slot.
+// the table is rehashed first if a new key can't be put without exceeding the load factor.
+func (m *swissMap[K, V]) prepareWrite(key K) (g *group[K, V], i int, found bool, h2 uint8) {
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	g, i, found = m.find(key, h1, h2)
+	g, i = m.makeRoom(key, h1, h2, g, i, found)
+	return g, i, found, h2
+}
+
+// makeRoom - rehashes the table if a new key can't be put into the slot <i> of <g> returned by find
+// without exceeding the load factor. returns the slot for the key, it's the same one if the table isn't rehashed.
+func (m *swissMap[K, V]) makeRoom(key K, h1 uint64, h2 uint8, g *group[K, V], i int, found bool) (*group[K, V], int) {
+	// putting into a deleted slot doesn't change the number of free slots
+	if !found && m.growthLeft == 0 && g.ctrlAt(i) == ctrlEmpty {
+		m.rehash(m.grownSize())
+		g, i, _ = m.find(key, h1, h2)
+	}
+	return g, i
+}
+
+// grownSize returns the number of groups for rehashing a full table.
+// if most of the used slots are tombstones the table keeps its size.
+func (m *swissMap[K, V]) grownSize() int {
+	if m.len*2 < maxLoad(len(m.groups)) {
+		return len(m.groups)
+	}
+	return len(m.groups) * 2
+}
+
+// rehash - moves all the elements into <n> new groups, tombstones are dropped
+func (m *swissMap[K, V]) rehash(n int) {
+	old := m.groups
+	m.groups = newGroups[K, V](n)
+	m.growthLeft = maxLoad(n) - m.len
+	m.tombstones = 0
+
+	mask := uint64(n - 1)
+	for gi := range old {
+		g := &old[gi]
+		for full := ^bitset(g.matchEmptyOrDeleted()) & msbs; full != 0; full = full.removeFirst() {
+			i := full.first()
+			h1, h2 := splitHash(m.hasher.Hash(g.keys[i]))
+
+			// keys are unique, so only an empty slot is searched
+			for seq := makeProbeSeq(h1, mask); ; seq = seq.next() {
+				dst := &m.groups[seq.offset]
+				if match := dst.matchEmpty(); match != 0 {
+					j := match.first()
+					dst.setCtrl(j, h2)
+					dst.keys[j] = g.keys[i]
+					dst.values[j] = g.values[i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// putAt - puts the value into the slot returned by prepareWrite
+func (m *swissMap[K, V]) putAt(g *group[K, V], i int, found bool, key K, h2 uint8, value V) {
+	if !found {
+		if g.ctrlAt(i) == ctrlDeleted {
+			m.tombstones--
+		} else {
+			m.growthLeft--
+		}
+		g.setCtrl(i, h2)
+		g.keys[i] = key
+		m.len++
+	}
+	g.values[i] = value
+}
+
+// deleteAt - deletes the element in the slot <i> of the group <g>
+func (m *swissMap[K, V]) deleteAt(g *group[K, V], i int) {
+	// if the group has an empty slot, no probe sequence has gone through it,
+	// so the slot can become empty. otherwise a tombstone keeps the sequences going.
+	if g.matchEmpty() != 0 {
+		g.setCtrl(i, ctrlEmpty)
+		m.growthLeft++
+	} else {
+		g.setCtrl(i, ctrlDeleted)
+		m.tombstones++
+	}
+	// zero the key and the value, so the GC doesn't keep objects they point to
+	g.keys[i] = *new(K)
+	g.values[i] = *new(V)
+	m.len--
+}
+
+func (m *swissMap[K, V]) Put(key K, value V) {
+	m.startWriting()
+
+	g, i, found, h2 := m.prepareWrite(key)
+	m.putAt(g, i, found, key, h2, value)
+
+	m.finishWriting()
+}
+
+func (m *swissMap[K, V]) Delete(key K) {
+	m.Pop(key)
+}
+
+func (m *swissMap[K, V]) Pop(key K) (value V, ok bool) {
+	m.startWriting()
+
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	g, i, found := m.find(key, h1, h2)
+	if found {
+		value, ok = g.values[i], true
+		m.deleteAt(g, i)
+	}
+
+	m.finishWriting()
+	return value, ok
+}
+
+func (m *swissMap[K, V]) Update(key K, f func(old V, ok bool) (V, bool)) {
+	m.startWriting()
+	// f may panic, the map must stay usable
+	defer m.finishWriting()
+
+	// look the key up first, the table is rehashed only if a new key is put
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	g, i, found := m.find(key, h1, h2)
+	var old V
+	if found {
+		old = g.values[i]
+	}
+	if v, ok := f(old, found); ok {
+		g, i = m.makeRoom(key, h1, h2, g, i, found)
+		m.putAt(g, i, found, key, h2, v)
+	}
+}
+
+func (m *swissMap[K, V]) GetOrPut(key K, value V) (actual V, loaded bool) {
+	m.startWriting()
+
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	g, i, found := m.find(key, h1, h2)
+	if found {
+		actual = g.values[i]
+	} else {
+		actual = value
+		g, i = m.makeRoom(key, h1, h2, g, i, found)
+		m.putAt(g, i, found, key, h2, value)
+	}
+
+	m.finishWriting()
+	return actual, found
+}
+
+func (m *swissMap[K, V]) Compute(key K, f func(old V, ok bool) (V, bool)) (actual V, ok bool) {
+	m.startWriting()
+	// f may panic, the map must stay usable
+	defer m.finishWriting()
+
+	h1, h2 := splitHash(m.hasher.Hash(key))
+	g, i, found := m.find(key, h1, h2)
+	var old V
+	if found {
+		old = g.values[i]
+	}
+	actual, ok = f(old, found)
+	if ok {
+		g, i = m.makeRoom(key, h1, h2, g, i, found)
+		m.putAt(g, i, found, key, h2, actual)
+	} else {
+		if found {
+			m.deleteAt(g, i)
+		}
+		actual = *new(V)
+	}
+
+	return actual, ok
+}
+
+func (m *swissMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	m.startWriting()
+
+	g, i, found, h2 := m.prepareWrite(key)
+	if found {
+		previous = g.values[i]
+	}
+	m.putAt(g, i, found, key, h2, value)
+
+	m.finishWriting()
+	return previous, found
+}
+
+// Range - iterates through the groups starting from a random one.
+// the table is always rehashed into new groups, so if it happens during
+// the iteration, the old groups are iterated and values are looked up in the new ones.
+func (m *swissMap[K, V]) Range(f func(k K, v V) bool) {
+	if m.len == 0 {
+		return
+	}
+
+	groups := m.groups
+	mask := uint64(len(groups) - 1)
+	start := uint64(0)
+	if !m.deterministic {
+		start = rand.Uint64()
+	}
+	for n := uint64(0); n <= mask; n++ {
+		g := &groups[(start+n)&mask]
+		for i := 0; i < groupSize; i++ {
+			key, value, ok := m.iterCell(groups, g, i)
+			if !ok {
+				continue
+			}
+			if !f(key, value) {
+				return
+			}
+		}
+	}
+}
+
+// iterCell - reads the i-th cell of the group for Range. returns false if there is no element.
+// like hiter.next, the cell is read as a map read, so concurrent writes are detected in the checked mode
+func (m *swissMap[K, V]) iterCell(groups []group[K, V], g *group[K, V], i int) (key K, value V, ok bool) {
+	if m.guard != nil {
+		m.guard.startReading("concurrent map iteration and map write")
+		defer m.guard.finishReading()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map iteration and map write")
+	}
+	if g.ctrlAt(i)&ctrlEmpty != 0 {
+		return key, value, false
+	}
+
+	key, value = g.keys[i], g.values[i]
+	if &groups[0] != &m.groups[0] && key == key {
+		// the table has been rehashed, the key could be updated or deleted.
+		// NaNs can't be looked up, the old value is returned for them
+		value, ok = m.Get2(key)
+		return key, value, ok
+	}
+	return key, value, true
+}
+
+func (m *swissMap[K, V]) RangeSorted(less func(a, b K) bool, f func(k K, v V) bool) {
+	rangeSorted(m.Range, less, f)
+}
+
+func (m *swissMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.Range(yield)
+	}
+}
+
+func (m *swissMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.Range(func(k K, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+func (m *swissMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ K, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+func (m *swissMap[K, V]) Len() int {
+	return m.len
+}
+
+func (m *swissMap[K, V]) Shrink() {
+	m.startWriting()
+	m.rehash(groupsFor(m.len))
+	m.finishWriting()
+}
+
+// Compact - rehashes the table at the same size, dropping tombstones
+func (m *swissMap[K, V]) Compact() {
+	m.startWriting()
+	m.rehash(len(m.groups))
+	m.finishWriting()
+}
+
+// Clear - removes all the elements keeping allocated groups
+func (m *swissMap[K, V]) Clear() {
+	m.startWriting()
+
+	for i := range m.groups {
+		m.groups[i] = group[K, V]{ctrl: lsbs * ctrlEmpty}
+	}
+	m.len = 0
+	m.tombstones = 0
+	m.growthLeft = maxLoad(len(m.groups))
+
+	m.finishWriting()
+}
+
+func (m *swissMap[K, V]) Reset(size int) {
+	m.startWriting()
+
+	m.groups = newGroups[K, V](groupsFor(size))
+	m.len = 0
+	m.tombstones = 0
+	m.growthLeft = maxLoad(len(m.groups))
+
+	m.finishWriting()
+}
+
+func (m *swissMap[K, V]) String() string {
+	return formatMap(m.Range, m.stringLess)
+}
+
+func (m *swissMap[K, V]) startWriting() {
+	if m.guard != nil {
+		m.guard.startWriting()
+	}
+	if m.flags&hashWriting != 0 {
+		panic("concurrent map writes")
+	}
+	m.flags ^= hashWriting
+}
+
+func (m *swissMap[K, V]) finishWriting() {
+	if m.flags&hashWriting == 0 {
+		panic("concurrent map writes")
+	}
+	m.flags &^= hashWriting
+	if m.guard != nil {
+		m.guard.finishWriting()
+	}
+}
+
+var (
+	_ Hashmap[string, int]      = (*swissMap[string, int])(nil)
+	_ Sequencer[string, int]    = (*swissMap[string, int])(nil)
+	_ Popper[string, int]       = (*swissMap[string, int])(nil)
+	_ Upserter[string, int]     = (*swissMap[string, int])(nil)
+	_ SortedRanger[string, int] = (*swissMap[string, int])(nil)
+	_ Shrinker                  = (*swissMap[string, int])(nil)
+	_ Clearer                   = (*swissMap[string, int])(nil)
+)
@@ -0,0 +1,131 @@
+package gomap
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+)
+
+func TestSwissMatch(t *testing.T) {
+	g := group[int, int]{ctrl: lsbs * ctrlEmpty}
+	g.setCtrl(1, 0x12)
+	g.setCtrl(3, ctrlDeleted)
+	g.setCtrl(5, 0x12)
+	g.setCtrl(6, 0x7f)
+
+	slots := func(b bitset) []int {
+		var res []int
+		for ; b != 0; b = b.removeFirst() {
+			res = append(res, b.first())
+		}
+		return res
+	}
+
+	isEqual(t, slots(g.matchH2(0x12)), []int{1, 5})
+	isEqual(t, slots(g.matchH2(0x7f)), []int{6})
+	isEqual(t, slots(g.matchEmpty()), []int{0, 2, 4, 7})
+	isEqual(t, slots(g.matchEmptyOrDeleted()), []int{0, 2, 3, 4, 7})
+	isEqual(t, g.ctrlAt(3), uint8(ctrlDeleted))
+}
+
+func TestSwiss(t *testing.T) {
+	m := NewSwiss[string, int](0).(*swissMap[string, int])
+
+	_, ok := m.Get2("a")
+	isEqual(t, ok, false)
+
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Put(fmt.Sprint(i), i)
+	}
+	isEqual(t, m.Len(), n)
+	for i := 0; i < n; i++ {
+		isEqual(t, m.Get(fmt.Sprint(i)), i)
+	}
+
+	m.Put("1", 100)
+	isEqual(t, m.Get("1"), 100)
+	isEqual(t, m.Len(), n)
+
+	for i := 0; i < n; i += 2 {
+		m.Delete(fmt.Sprint(i))
+	}
+	isEqual(t, m.Len(), n/2)
+	isEqual(t, len(maps.Collect(m.All())), n/2)
+
+	m.Shrink()
+	isEqual(t, len(m.groups), groupsFor(n/2))
+	for i := 1; i < n; i += 2 {
+		_, ok := m.Get2(fmt.Sprint(i))
+		isEqual(t, ok, true)
+	}
+
+	m.Clear()
+	isEqual(t, m.Len(), 0)
+	isEqual(t, m.String(), "go-map[]")
+}
+
+func TestSwissTombstones(t *testing.T) {
+	// all keys are in the same group, so deleted slots become tombstones
+	hasher := HasherFunc[int](func(k int) uint64 { return uint64(k%4) << 7 })
+	m := NewSwiss(0, WithHasher[int, int](hasher)).(*swissMap[int, int])
+
+	for round := 0; round < 100; round++ {
+		for i := 0; i < 20; i++ {
+			m.Put(round*100+i, i)
+		}
+		for i := 0; i < 20; i++ {
+			v, ok := m.Pop(round*100 + i)
+			isEqual(t, ok, true)
+			isEqual(t, v, i)
+		}
+	}
+	isEqual(t, m.Len(), 0)
+	// tombstones don't make the table grow
+	isEqual(t, len(m.groups) <= 4, true)
+
+	m.Compact()
+	isEqual(t, m.tombstones, 0)
+}
+
+func TestSwissUpserts(t *testing.T) {
+	t.Run("no rehash without an insert", func(t *testing.T) {
+		m := NewSwiss[int, int](0).(*swissMap[int, int])
+		for i := 0; m.growthLeft > 0; i++ {
+			m.Put(i, i)
+		}
+		groups := &m.groups[0]
+
+		keep := func(old int, ok bool) (int, bool) { return old + 1, ok }
+		m.Update(0, keep)
+		m.Update(-1, keep)
+		m.Compute(1, keep)
+		m.Compute(-1, keep)
+		m.GetOrPut(2, 0)
+		isEqual(t, &m.groups[0] == groups, true)
+		isEqual(t, m.Get(0), 1)
+		isEqual(t, m.Get(1), 2)
+		isEqual(t, m.Get(2), 2)
+
+		m.GetOrPut(-1, -1)
+		isEqual(t, &m.groups[0] == groups, false)
+		isEqual(t, m.Get(-1), -1)
+	})
+
+	t.Run("panicking callback", func(t *testing.T) {
+		for _, opts := range [][]Option[int, int]{nil, {WithAccessChecks[int, int]()}} {
+			m := NewSwiss(0, opts...).(*swissMap[int, int])
+			m.Put(1, 1)
+
+			boom := func(old int, ok bool) (int, bool) { panic("boom") }
+			mustPanic(t, "boom", func() { m.Update(1, boom) })
+			mustPanic(t, "boom", func() { m.Compute(2, boom) })
+
+			// the map isn't left marked as being written
+			m.Put(2, 2)
+			isEqual(t, m.Get(1), 1)
+			isEqual(t, m.Get(2), 2)
+			isEqual(t, m.Len(), 2)
+		}
+	})
+}
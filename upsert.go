@@ -0,0 +1,159 @@
+package gomap
+
+// Read-modify-write operations. Unlike Get2 followed by Put they hash the key once
+// and find its cell in a single pass over the bucket chain, the value is changed or deleted in that cell.
+// The map is prepared for a write like Put does it, and the chain is walked again,
+// only to add a new key or to change a key in an old bucket which isn't evacuated yet.
+// So the map doesn't grow if nothing is added.
+
+// Upserter - implemented by maps with read-modify-write operations.
+type Upserter[K comparable, V any] interface {
+	// calls f with the current value for the key and whether it exists.
+	// if f returns true, the returned value is put into the map,
+	// otherwise the map isn't changed.
+	// f must not access the map.
+	Update(key K, f func(old V, ok bool) (V, bool))
+	// returns the existing value for the key and true if present.
+	// otherwise puts the given value and returns it and false.
+	GetOrPut(key K, value V) (actual V, loaded bool)
+	// calls f with the current value for the key and whether it exists.
+	// if f returns true, the returned value is put into the map,
+	// otherwise the key is deleted. returns the resulting value and whether it exists.
+	// f must not access the map.
+	Compute(key K, f func(old V, ok bool) (V, bool)) (actual V, ok bool)
+	// puts the value and returns the previous one and true if the key existed
+	Swap(key K, value V) (previous V, loaded bool)
+}
+
+var _ Upserter[string, int] = (*hmap[string, int])(nil)
+
+func (h *hmap[K, V]) Update(key K, f func(old V, ok bool) (V, bool)) {
+	h.update(key, h.hasher.Hash(key), f)
+}
+
+// update - Update for the key with the precomputed hash
+func (h *hmap[K, V]) update(key K, hash uint64, f func(old V, ok bool) (V, bool)) {
+	h.startWriting()
+	// f may panic, the map must stay usable
+	defer h.finishWriting()
+
+	_, bkt, i, found := h.lookup(key, hash)
+
+	var old V
+	if found {
+		old = bkt.values[i]
+	}
+	if v, ok := f(old, found); ok {
+		h.storeAt(key, hash, bkt, i, found, v)
+	}
+}
+
+func (h *hmap[K, V]) GetOrPut(key K, value V) (actual V, loaded bool) {
+	return h.getOrPut(key, h.hasher.Hash(key), value)
+}
+
+// getOrPut - GetOrPut for the key with the precomputed hash
+func (h *hmap[K, V]) getOrPut(key K, hash uint64, value V) (actual V, loaded bool) {
+	h.startWriting()
+
+	_, bkt, i, found := h.lookup(key, hash)
+	if found {
+		actual = bkt.values[i]
+	} else {
+		actual = value
+		h.storeAt(key, hash, bkt, i, found, value)
+	}
+
+	h.finishWriting()
+	return actual, found
+}
+
+func (h *hmap[K, V]) Compute(key K, f func(old V, ok bool) (V, bool)) (actual V, ok bool) {
+	return h.compute(key, h.hasher.Hash(key), f)
+}
+
+// compute - Compute for the key with the precomputed hash
+func (h *hmap[K, V]) compute(key K, hash uint64, f func(old V, ok bool) (V, bool)) (actual V, ok bool) {
+	h.startWriting()
+	// f may panic, the map must stay usable
+	defer h.finishWriting()
+
+	head, bkt, i, found := h.lookup(key, hash)
+
+	var old V
+	if found {
+		old = bkt.values[i]
+	}
+	actual, ok = f(old, found)
+	if ok {
+		h.storeAt(key, hash, bkt, i, found, actual)
+	} else {
+		if found {
+			head.deleteAt(bkt, i)
+			h.deleted()
+			h.growStep(hash)
+		}
+		actual = *new(V)
+	}
+
+	return actual, ok
+}
+
+func (h *hmap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	return h.swap(key, h.hasher.Hash(key), value)
+}
+
+// swap - Swap for the key with the precomputed hash
+func (h *hmap[K, V]) swap(key K, hash uint64, value V) (previous V, loaded bool) {
+	h.startWriting()
+
+	tophash, targetBucket := h.prepareWrite(hash)
+	bkt, i, found := h.buckets[targetBucket].find(key, tophash)
+
+	if found {
+		previous = bkt.values[i]
+	}
+	h.putAt(bkt, i, found, key, tophash, value)
+
+	h.finishWriting()
+	return previous, found
+}
+
+// storeAt - puts the value for the key into the cell found by lookup.
+// an existing key in the current buckets is changed in place. otherwise the key is put like Put does it:
+// the map may grow and the old bucket is evacuated first, then the cell is found in the new bucket
+func (h *hmap[K, V]) storeAt(key K, hash uint64, bkt *bucket[K, V], i int, found bool, value V) {
+	if found && !h.inOldBucket(hash) {
+		bkt.values[i] = value
+		h.growStep(hash)
+		return
+	}
+
+	tophash, targetBucket := h.prepareWrite(hash)
+	bkt, i, found = h.buckets[targetBucket].find(key, tophash)
+	h.putAt(bkt, i, found, key, tophash, value)
+}
+
+// growStep - evacuates old buckets like writes do, so changes in place make progress on growth.
+// evacuation doesn't move cells of the current buckets, the cells found by lookup stay valid
+func (h *hmap[K, V]) growStep(hash uint64) {
+	if h.isGrowing() {
+		h.growWork(hash & bucketMask(h.B))
+	}
+}
+
+// putAt - puts the value into the cell returned by bucket.find.
+// if the key doesn't exist, it's put into the empty cell or into a new overflow bucket
+func (h *hmap[K, V]) putAt(bkt *bucket[K, V], i int, found bool, key K, tophash uint8, value V) {
+	if found {
+		bkt.values[i] = value
+		return
+	}
+
+	if i == bucketSize {
+		bkt = h.newOverflow(bkt)
+		i = 0
+	}
+	bkt.putAt(key, tophash, value, uint(i))
+	h.len++
+}
@@ -0,0 +1,240 @@
+package gomap
+
+import (
+	"testing"
+)
+
+func TestUpsert(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		m := New[string, int](8).(*hmap[string, int])
+		inc := func(old int, ok bool) (int, bool) { return old + 1, true }
+
+		m.Update("a", inc)
+		m.Update("a", inc)
+		isEqual(t, m.Get("a"), 2)
+
+		m.Update("b", func(old int, ok bool) (int, bool) {
+			isEqual(t, ok, false)
+			return 10, false
+		})
+		_, ok := m.Get2("b")
+		isEqual(t, ok, false)
+		isEqual(t, m.Len(), 1)
+	})
+
+	t.Run("get or put", func(t *testing.T) {
+		m := New[string, int](8).(*hmap[string, int])
+
+		v, loaded := m.GetOrPut("a", 1)
+		isEqual(t, v, 1)
+		isEqual(t, loaded, false)
+
+		v, loaded = m.GetOrPut("a", 2)
+		isEqual(t, v, 1)
+		isEqual(t, loaded, true)
+		isEqual(t, m.Len(), 1)
+	})
+
+	t.Run("compute", func(t *testing.T) {
+		m := New[string, int](8).(*hmap[string, int])
+
+		v, ok := m.Compute("a", func(old int, ok bool) (int, bool) { return 5, true })
+		isEqual(t, v, 5)
+		isEqual(t, ok, true)
+
+		v, ok = m.Compute("a", func(old int, ok bool) (int, bool) {
+			isEqual(t, old, 5)
+			isEqual(t, ok, true)
+			return old * 2, true
+		})
+		isEqual(t, v, 10)
+		isEqual(t, m.Get("a"), 10)
+
+		// delete
+		v, ok = m.Compute("a", func(old int, ok bool) (int, bool) { return old, false })
+		isEqual(t, v, 0)
+		isEqual(t, ok, false)
+		_, ok = m.Get2("a")
+		isEqual(t, ok, false)
+		isEqual(t, m.Len(), 0)
+
+		// nothing to delete
+		_, ok = m.Compute("b", func(old int, ok bool) (int, bool) { return 1, false })
+		isEqual(t, ok, false)
+		isEqual(t, m.Len(), 0)
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		m := New[string, int](8).(*hmap[string, int])
+
+		prev, loaded := m.Swap("a", 1)
+		isEqual(t, prev, 0)
+		isEqual(t, loaded, false)
+
+		prev, loaded = m.Swap("a", 2)
+		isEqual(t, prev, 1)
+		isEqual(t, loaded, true)
+		isEqual(t, m.Get("a"), 2)
+	})
+
+	t.Run("hash once", func(t *testing.T) {
+		if validateWrites {
+			t.Skip("the invariants are checked after every write, it hashes all the keys")
+		}
+		hashes := 0
+		hasher := HasherFunc[int](func(k int) uint64 {
+			hashes++
+			return uint64(k)
+		})
+		m := NewWithOptions(100, WithHasher[int, int](hasher)).(*hmap[int, int])
+
+		m.Update(1, func(old int, ok bool) (int, bool) { return 1, true })
+		m.GetOrPut(2, 2)
+		m.Compute(3, func(old int, ok bool) (int, bool) { return 3, true })
+		m.Swap(4, 4)
+		isEqual(t, hashes, 4)
+	})
+
+	t.Run("no growth without a write", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		for i := 0; i < bucketSize; i++ {
+			m.Put(i, i)
+		}
+
+		// adding a key would start growth as the map is full, like Put does
+		m.Update(100, func(old int, ok bool) (int, bool) { return 1, false })
+		m.Compute(100, func(old int, ok bool) (int, bool) { return 1, false })
+		v, loaded := m.GetOrPut(2, 20)
+		isEqual(t, v, 2)
+		isEqual(t, loaded, true)
+		m.Update(1, func(old int, ok bool) (int, bool) { return -1, true })
+		m.Compute(3, func(old int, ok bool) (int, bool) { return -3, true })
+		isEqual(t, m.isGrowing(), false)
+		isEqual(t, m.B, uint8(0))
+		isEqual(t, m.Get(1), -1)
+		isEqual(t, m.Get(3), -3)
+
+		m.Update(100, func(old int, ok bool) (int, bool) { return 100, true })
+		isEqual(t, m.B, uint8(1))
+		isEqual(t, m.Get(100), 100)
+	})
+
+	t.Run("single pass", func(t *testing.T) {
+		if validateWrites {
+			t.Skip("the invariants are checked after every write, it looks up all the keys")
+		}
+		finds := 0
+		testHookFind = func() { finds++ }
+		defer func() { testHookFind = nil }()
+
+		m := New[int, int](100).(*hmap[int, int])
+		for i := 0; i < 10; i++ {
+			m.Put(i, i)
+		}
+		inc := func(old int, ok bool) (int, bool) { return old + 1, true }
+
+		for _, op := range []struct {
+			name  string
+			f     func()
+			finds int
+		}{
+			{"update", func() { m.Update(1, inc) }, 1},
+			{"update, no write", func() { m.Update(100, func(int, bool) (int, bool) { return 0, false }) }, 1},
+			{"get or put, existing", func() { m.GetOrPut(2, 20) }, 1},
+			{"compute", func() { m.Compute(3, inc) }, 1},
+			{"compute, delete", func() { m.Compute(4, func(int, bool) (int, bool) { return 0, false }) }, 1},
+			{"swap", func() { m.Swap(5, 50) }, 1},
+			// a new key is put like Put does, into the cell found after the map is prepared for the write
+			{"update, new key", func() { m.Update(200, inc) }, 2},
+			{"get or put, new key", func() { m.GetOrPut(201, 1) }, 2},
+		} {
+			finds = 0
+			op.f()
+			if finds != op.finds {
+				t.Errorf("%s: %d passes over the bucket chain, want %d", op.name, finds, op.finds)
+			}
+		}
+
+		isEqual(t, m.Get(1), 2)
+		isEqual(t, m.Get(3), 4)
+		_, ok := m.Get2(4)
+		isEqual(t, ok, false)
+		isEqual(t, m.Get(200), 1)
+		mustValidate(t, m)
+	})
+
+	t.Run("during growth", func(t *testing.T) {
+		for _, sameSize := range []bool{false, true} {
+			m := New[int, int](0).(*hmap[int, int])
+			for i := 0; i < 100; i++ {
+				m.Put(i, i)
+			}
+			m.ForceGrow(sameSize)
+			m.EvacuateStep(1)
+
+			// keys are changed and deleted both in the evacuated buckets and in the old ones
+			for i := 0; i < 100; i++ {
+				m.Compute(i, func(old int, ok bool) (int, bool) { return -old, i%2 == 0 })
+			}
+			mustValidate(t, m)
+			want := map[int]int{}
+			for i := 0; i < 100; i += 2 {
+				want[i] = -i
+			}
+			checkContents(t, m, want)
+
+			m.FinishGrowth()
+			mustValidate(t, m)
+			checkContents(t, m, want)
+		}
+	})
+
+	t.Run("panicking callback", func(t *testing.T) {
+		for _, opts := range [][]Option[int, int]{nil, {WithAccessChecks[int, int]()}} {
+			m := NewWithOptions(0, opts...).(*hmap[int, int])
+			m.Put(1, 1)
+
+			boom := func(old int, ok bool) (int, bool) { panic("boom") }
+			mustPanic(t, "boom", func() { m.Update(1, boom) })
+			mustPanic(t, "boom", func() { m.Compute(2, boom) })
+
+			// the map isn't left marked as being written
+			m.Put(2, 2)
+			isEqual(t, m.Get(1), 1)
+			isEqual(t, m.Get(2), 2)
+			isEqual(t, m.Len(), 2)
+		}
+	})
+
+	t.Run("growth", func(t *testing.T) {
+		m := New[int, int](0).(*hmap[int, int])
+		n := 1000
+		for i := 0; i < n; i++ {
+			switch i % 4 {
+			case 0:
+				m.Update(i, func(old int, ok bool) (int, bool) { return i, true })
+			case 1:
+				m.GetOrPut(i, i)
+			case 2:
+				m.Compute(i, func(old int, ok bool) (int, bool) { return i, true })
+			case 3:
+				m.Swap(i, i)
+			}
+		}
+		isEqual(t, m.Len(), n)
+		for i := 0; i < n; i++ {
+			isEqual(t, m.Get(i), i)
+		}
+
+		// delete everything, the map shrinks meanwhile
+		for i := 0; i < n; i++ {
+			m.Compute(i, func(old int, ok bool) (int, bool) {
+				isEqual(t, ok, true)
+				isEqual(t, old, i)
+				return 0, false
+			})
+		}
+		isEqual(t, m.Len(), 0)
+		isEqual(t, m.B < 5, true)
+	})
+}
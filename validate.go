@@ -0,0 +1,154 @@
+package gomap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validate - checks the internal invariants of the map and returns the first violation found.
+// it's used by tests and fuzzing, and after every write with the gomapdebug build tag.
+func (m *hmap[K, V]) validate() error {
+	if err := m.validateFlags(); err != nil {
+		return err
+	}
+	if uint64(len(m.buckets)) != bucketsNum(m.B) {
+		return fmt.Errorf("%d buckets, want %d for B=%d", len(m.buckets), bucketsNum(m.B), m.B)
+	}
+
+	v := validator[K, V]{m: m, seen: make(map[K]cellPos, m.len)}
+	noverflow := 0
+	for i := range m.buckets {
+		overflow, err := v.validateChain(&m.buckets[i], uint64(i), bucketMask(m.B), "bucket")
+		if err != nil {
+			return err
+		}
+		noverflow += overflow
+	}
+	if uint32(noverflow) != m.noverflow {
+		return fmt.Errorf("noverflow is %d, but there are %d overflow buckets", m.noverflow, noverflow)
+	}
+
+	if m.isGrowing() {
+		oldbuckets := *m.oldbuckets
+		if uint64(len(oldbuckets)) != m.numOldBuckets() {
+			return fmt.Errorf("%d old buckets, want %d", len(oldbuckets), m.numOldBuckets())
+		}
+		// the growth is finished as soon as the last old bucket is evacuated
+		if m.numEvacuated >= m.numOldBuckets() {
+			return fmt.Errorf("numEvacuated is %d of %d old buckets, but the growth isn't finished", m.numEvacuated, m.numOldBuckets())
+		}
+
+		for i := range oldbuckets {
+			b := &oldbuckets[i]
+			if !b.isEvacuated() {
+				if uint64(i) < m.numEvacuated {
+					return fmt.Errorf("old bucket %d is below numEvacuated(%d), but isn't evacuated", i, m.numEvacuated)
+				}
+				if _, err := v.validateChain(b, uint64(i), m.oldBucketMask(), "old bucket"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			for ; b != nil; b = b.overflow {
+				for j, top := range b.tophash {
+					if top < evacuatedFirst || top > evacuatedEmpty {
+						return fmt.Errorf("old bucket %d: cell %d has state %d in an evacuated bucket", i, j, top)
+					}
+				}
+			}
+		}
+	}
+
+	if v.live != m.len {
+		return fmt.Errorf("len is %d, but there are %d elements", m.len, v.live)
+	}
+
+	return nil
+}
+
+// validateFlags - checks that the flags match the map state.
+func (m *hmap[K, V]) validateFlags() error {
+	if m.flags&hashWriting != 0 {
+		return errors.New("hashWriting flag is set outside of a write")
+	}
+	if m.flags&sameSizeGrow != 0 && m.flags&shrinking != 0 {
+		return errors.New("sameSizeGrow and shrinking flags are set together")
+	}
+	if !m.isGrowing() && m.flags&(sameSizeGrow|shrinking) != 0 {
+		return fmt.Errorf("growth flags %b are set without growth", m.flags)
+	}
+	if m.isGrowing() && m.growsBigger() && m.B == 0 {
+		return errors.New("the map grows bigger into a single bucket")
+	}
+	return nil
+}
+
+type validator[K comparable, V any] struct {
+	m    *hmap[K, V]
+	seen map[K]cellPos // where each key has been met
+	live int
+}
+
+// cellPos - a position of a cell for error messages
+type cellPos struct {
+	name     string // "bucket" or "old bucket"
+	bucket   uint64
+	overflow int // # of the overflow bucket in the chain, 0 for the first one
+	cell     int
+}
+
+func (p cellPos) String() string {
+	return fmt.Sprintf("%s %d(overflow %d) cell %d", p.name, p.bucket, p.overflow, p.cell)
+}
+
+// validateChain - checks the bucket <idx> and its overflow buckets, which aren't evacuated.
+// returns the number of overflow buckets.
+func (v *validator[K, V]) validateChain(head *bucket[K, V], idx uint64, mask uint64, name string) (overflow int, err error) {
+	prev := uint8(minTopHash) // a filled cell before the first one
+	for b := head; b != nil; b = b.overflow {
+		if b != head {
+			overflow++
+		}
+
+		for i, top := range b.tophash {
+			where := cellPos{name: name, bucket: idx, overflow: overflow, cell: i}
+			switch {
+			case prev == emptyRest && top != emptyRest:
+				return 0, fmt.Errorf("%s: state %d after emptyRest", where, top)
+			case prev == emptyCell && top == emptyRest:
+				return 0, fmt.Errorf("%s: emptyRest after emptyCell, the cell before should be emptyRest", where)
+			case top > emptyCell && top < minTopHash:
+				return 0, fmt.Errorf("%s: evacuation state %d in a bucket which isn't evacuated", where, top)
+			}
+			prev = top
+			if isCellEmpty(top) {
+				continue
+			}
+
+			v.live++
+			key := b.keys[i]
+			// NaNs keep the tophash they were put with and are moved by it during growth
+			if key != key {
+				continue
+			}
+
+			hash := v.m.hasher.Hash(key)
+			if want := topHash(hash); top != want {
+				return 0, fmt.Errorf("%s: tophash %d, want %d for key %v", where, top, want, key)
+			}
+			if hash&mask != idx {
+				return 0, fmt.Errorf("%s: key %v belongs to %s %d", where, key, name, hash&mask)
+			}
+			if other, ok := v.seen[key]; ok {
+				return 0, fmt.Errorf("%s: key %v is duplicated in %s", where, key, other)
+			}
+			v.seen[key] = where
+		}
+	}
+
+	if prev == emptyCell {
+		return 0, fmt.Errorf("%s %d: the chain ends with emptyCell instead of emptyRest", name, idx)
+	}
+	return overflow, nil
+}
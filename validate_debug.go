@@ -0,0 +1,30 @@
+//go:build gomapdebug
+
+package gomap
+
+import "fmt"
+
+// the map invariants are checked after every write
+const validateWrites = true
+
+// Validate - checks the internal invariants of a map created by New or NewConcurrent.
+// available with the gomapdebug build tag only.
+func Validate[K comparable, V any](m Hashmap[K, V]) error {
+	switch m := m.(type) {
+	case *hmap[K, V]:
+		return m.validate()
+	case *ConcurrentMap[K, V]:
+		for i := range m.shards {
+			s := &m.shards[i]
+			s.RLock()
+			err := s.m.validate()
+			s.RUnlock()
+			if err != nil {
+				return fmt.Errorf("shard %d: %w", i, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("validation isn't supported for %T", m)
+	}
+}
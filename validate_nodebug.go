@@ -0,0 +1,6 @@
+//go:build !gomapdebug
+
+package gomap
+
+// the map invariants are checked after every write with the gomapdebug build tag only
+const validateWrites = false
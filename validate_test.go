@@ -0,0 +1,169 @@
+package gomap
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustValidate[K comparable, V any](t *testing.T, m *hmap[K, V]) {
+	t.Helper()
+	if err := m.validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	identity := HasherFunc[int](func(k int) uint64 { return uint64(k) })
+	// a map with B=2 and keys 0..9, the keys 0, 4 and 8 are in the first bucket
+	newMap := func() *hmap[int, int] {
+		m := NewWithOptions(20, WithHasher[int, int](identity)).(*hmap[int, int])
+		for i := 0; i < 10; i++ {
+			m.Put(i, i)
+		}
+		mustValidate(t, m)
+		return m
+	}
+	// growingMap - a map in the middle of the growth from B=2 to B=3
+	growingMap := func() *hmap[int, int] {
+		m := newMap()
+		m.startGrowth()
+		m.evacuate(0)
+		mustValidate(t, m)
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func() *hmap[int, int]
+		want    string
+	}{
+		{
+			name: "len",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.len++
+				return m
+			},
+			want: "len is 11, but there are 10 elements",
+		},
+		{
+			name: "tophash",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.buckets[0].tophash[1]++
+				return m
+			},
+			want: "bucket 0(overflow 0) cell 1: tophash 6, want 5 for key 4",
+		},
+		{
+			name: "key placement",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.buckets[0].keys[1] = 5
+				return m
+			},
+			want: "bucket 0(overflow 0) cell 1: key 5 belongs to bucket 1",
+		},
+		{
+			name: "duplicates",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.buckets[0].keys[1] = 0
+				return m
+			},
+			want: "bucket 0(overflow 0) cell 1: key 0 is duplicated in bucket 0(overflow 0) cell 0",
+		},
+		{
+			name: "filled cell after emptyRest",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.buckets[0].tophash[1] = emptyRest
+				return m
+			},
+			want: "bucket 0(overflow 0) cell 2: state 5 after emptyRest",
+		},
+		{
+			name: "emptyCell at the end",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.buckets[0].tophash[2] = emptyCell
+				m.len--
+				return m
+			},
+			want: "bucket 0(overflow 0) cell 3: emptyRest after emptyCell",
+		},
+		{
+			name: "noverflow",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.noverflow++
+				return m
+			},
+			want: "noverflow is 1, but there are 0 overflow buckets",
+		},
+		{
+			name: "growth flags",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.flags |= sameSizeGrow
+				return m
+			},
+			want: "growth flags 1000 are set without growth",
+		},
+		{
+			name: "hashWriting",
+			corrupt: func() *hmap[int, int] {
+				m := newMap()
+				m.flags |= hashWriting
+				return m
+			},
+			want: "hashWriting flag is set outside of a write",
+		},
+		{
+			name: "not evacuated below numEvacuated",
+			corrupt: func() *hmap[int, int] {
+				m := growingMap()
+				m.numEvacuated = 2
+				return m
+			},
+			want: "old bucket 1 is below numEvacuated(2), but isn't evacuated",
+		},
+		{
+			name: "old bucket placement",
+			corrupt: func() *hmap[int, int] {
+				m := growingMap()
+				(*m.oldbuckets)[1].keys[0] = 2
+				return m
+			},
+			want: "old bucket 1(overflow 0) cell 0: key 2 belongs to old bucket 2",
+		},
+		{
+			name: "filled cell in an evacuated bucket",
+			corrupt: func() *hmap[int, int] {
+				m := growingMap()
+				(*m.oldbuckets)[0].tophash[1] = minTopHash
+				return m
+			},
+			want: "old bucket 0: cell 1 has state 5 in an evacuated bucket",
+		},
+		{
+			name: "key in the old and the new buckets",
+			corrupt: func() *hmap[int, int] {
+				m := growingMap()
+				m.buckets[1].putAt(1, topHash(1), 1, 0)
+				m.len++
+				return m
+			},
+			want: "old bucket 1(overflow 0) cell 0: key 1 is duplicated in bucket 1(overflow 0) cell 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.corrupt().validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}